@@ -0,0 +1,265 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// Ability is a limited use weapon a player can use instead of firing
+type Ability string
+
+const (
+	// AbilitySonar reveals whether any ship afloat is in a 3x3 area
+	AbilitySonar Ability = "sonar"
+
+	// AbilityAirstrike fires at a segment of a row
+	AbilityAirstrike Ability = "airstrike"
+
+	// AbilityTorpedo travels in a direction until it hits a ship
+	AbilityTorpedo Ability = "torpedo"
+)
+
+// AirstrikeLength is the number of locations an airstrike fires at
+const AirstrikeLength = 3
+
+// Direction is the way a torpedo travels across the board
+type Direction string
+
+const (
+	DirectionUp    Direction = "up"
+	DirectionDown  Direction = "down"
+	DirectionLeft  Direction = "left"
+	DirectionRight Direction = "right"
+)
+
+var directionSteps = map[Direction]Coord{
+	DirectionUp:    {X: -1},
+	DirectionDown:  {X: 1},
+	DirectionLeft:  {Y: -1},
+	DirectionRight: {Y: 1},
+}
+
+/*
+UseAbilityEventMessage is sent by the Client to use an ability instead of firing.
+Location is the centre of a sonar sweep, the start of an airstrike or where a torpedo
+is launched from. Direction is only used by torpedoes. Target is the player the ability
+is used against, the next player in the turn order when it is not set
+*/
+type UseAbilityEventMessage struct {
+	EventMessage
+	Ability   Ability
+	Location  Coord
+	Direction Direction
+	Target    int
+}
+
+// AbilityResultEventMessage is sent to the player with what a sonar sweep found
+type AbilityResultEventMessage struct {
+	Ability   Ability
+	Location  Coord
+	Found     bool
+	Remaining int
+}
+
+// UseAbility uses an ability in a game the player is playing in
+func UseAbility(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message UseAbilityEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	useAbility(db, producer, gameID, message, userID)
+}
+
+/*
+useAbility resolves an ability on the server. Using an ability takes the player's turn.
+Airstrikes and torpedoes fire real shots which are played like any other volley. A sonar
+sweep fires nothing so only the player who used it learns what it found
+*/
+func useAbility(db *sql.DB, producer *kafka.Producer, gameID int, message UseAbilityEventMessage, userID int) {
+
+	if !checkTurn(db, producer, gameID, userID) {
+		return
+	}
+
+	rules := FindRuleSetForGame(db, gameID)
+	remaining := remainingAbilities(db, gameID, userID, rules)
+
+	if _, ok := rules.Abilities[message.Ability]; !ok {
+		PublishErrorEvent(producer, "Ability is not available in this game", userID, gameID)
+		return
+	}
+
+	if remaining[message.Ability] < 1 {
+		PublishErrorEvent(producer, "Ability has been used up", userID, gameID)
+		return
+	}
+
+	if !rules.isOnBoard(message.Location) {
+		PublishErrorEvent(producer, "Ability is outside the board", userID, gameID)
+		return
+	}
+
+	seats := FindSeatsForGame(db, gameID)
+
+	targetID := targetFor(seats, userID, message.Target)
+	if targetID == -1 {
+		PublishErrorEvent(producer, "That player can not be targeted", userID, gameID)
+		return
+	}
+
+	ships := FindShipsForPlayer(db, gameID, targetID)
+
+	switch message.Ability {
+	case AbilitySonar:
+		found := sonarSweep(ships, message.Location)
+
+		nextID := nextPlayer(seats, userID)
+		now := time.Now()
+		deadline := FindClockForGame(db, gameID).turnDeadline(now, nextID)
+
+		// The sweep uses up the ability in the same transaction as the turn it is played on
+		moveNumber, err := PlayMoveInDatabase(db, gameID, userID, nextID, deadline, now, func(tx *sql.Tx, number int) error {
+			return recordAbilityUseInTx(tx, gameID, userID, message.Ability, number, message.Location)
+		})
+		if err != nil {
+			PublishErrorEvent(producer, err.Error(), userID, gameID)
+			return
+		}
+		if moveNumber == -1 {
+			PublishErrorEvent(producer, "It is not your turn", userID, gameID)
+			return
+		}
+
+		abilityResultMessage := EventMessage{
+			Event:  AbilityResultEvent,
+			To:     userID,
+			GameID: gameID,
+			Payload: AbilityResultEventMessage{
+				Ability:   message.Ability,
+				Location:  message.Location,
+				Found:     found,
+				Remaining: remaining[message.Ability] - 1,
+			},
+		}
+
+		abilityResultMessage.Send(producer)
+
+		PublishGameUpdates(db, producer, gameID)
+		playBotTurn(db, producer, gameID)
+
+	case AbilityAirstrike:
+		targets := airstrikeTargets(rules, message.Location, FindShotsAtPlayer(db, gameID, targetID))
+		if len(targets) == 0 {
+			PublishErrorEvent(producer, "Every location in the airstrike has already been fired at", userID, gameID)
+			return
+		}
+
+		playVolley(db, producer, gameID, userID, targetID, message.Location, targets, message.Ability)
+
+	case AbilityTorpedo:
+		if _, ok := directionSteps[message.Direction]; !ok {
+			PublishErrorEvent(producer, fmt.Sprintf("Unknown direction %s", message.Direction), userID, gameID)
+			return
+		}
+
+		targets := torpedoTarget(rules, ships, message.Location, message.Direction)
+		playVolley(db, producer, gameID, userID, targetID, message.Location, targets, message.Ability)
+	}
+}
+
+// remainingAbilities is how many uses of each ability of the rule set the player has left
+func remainingAbilities(db *sql.DB, gameID int, playerID int, rules RuleSet) map[Ability]int {
+
+	if len(rules.Abilities) == 0 {
+		return nil
+	}
+
+	used := CountAbilityUses(db, gameID, playerID)
+	remaining := make(map[Ability]int)
+
+	for ability, uses := range rules.Abilities {
+		remaining[ability] = uses - used[ability]
+	}
+
+	return remaining
+}
+
+// sonarSweep checks if any ship afloat has a location in the 3x3 area around the centre
+func sonarSweep(ships []Ship, centre Coord) bool {
+
+	for _, ship := range ships {
+		if ship.Sunk {
+			continue
+		}
+
+		for _, location := range ship.Location {
+			if abs(location.X-centre.X) <= 1 && abs(location.Y-centre.Y) <= 1 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// airstrikeTargets lists the locations along the row from the start which are on the board
+// and have not been fired at. Islands can not be fired at so they are left out
+func airstrikeTargets(rules RuleSet, start Coord, fired []Coord) []Coord {
+
+	firedAt := make(map[Coord]bool)
+	for _, shot := range fired {
+		firedAt[Coord{X: shot.X, Y: shot.Y}] = true
+	}
+
+	var targets []Coord
+
+	for i := 0; i < AirstrikeLength; i++ {
+		target := Coord{X: start.X, Y: start.Y + i}
+
+		if rules.isOnBoard(target) && !rules.isIsland(target) && !firedAt[target] {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+/*
+torpedoTarget follows a torpedo from where it is launched until it reaches a ship location
+which has not been hit. Holes already made do not stop it. The torpedo fires at that one
+location, or at nothing if it runs aground on an island or leaves the board
+*/
+func torpedoTarget(rules RuleSet, ships []Ship, start Coord, direction Direction) []Coord {
+
+	step := directionSteps[direction]
+
+	for location := start; rules.isOnBoard(location); location = (Coord{X: location.X + step.X, Y: location.Y + step.Y}) {
+		if rules.isIsland(location) {
+			return nil
+		}
+
+		shipIndex, locationIndex := findShipAtLocation(ships, location)
+
+		if shipIndex != -1 && !ships[shipIndex].Location[locationIndex].Hit {
+			return []Coord{location}
+		}
+	}
+
+	return nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
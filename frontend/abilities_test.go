@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSonarSweep(t *testing.T) {
+
+	ships := []Ship{
+		Ship{Size: 2, Location: []Coord{Coord{X: 4, Y: 4}, Coord{X: 4, Y: 5}}},
+		Ship{Size: 2, Sunk: true, Location: []Coord{Coord{X: 0, Y: 0, Hit: true}, Coord{X: 0, Y: 1, Hit: true}}},
+	}
+
+	tt := []struct {
+		name     string
+		centre   Coord
+		expected bool
+	}{
+		{"When a ship is at the centre", Coord{X: 4, Y: 4}, true},
+		{"When a ship is at the edge of the area", Coord{X: 3, Y: 6}, true},
+		{"When the area is empty", Coord{X: 7, Y: 7}, false},
+		{"When only a sunk ship is in the area", Coord{X: 1, Y: 1}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if found := sonarSweep(ships, tc.centre); found != tc.expected {
+				t.Fatalf("Expecting sonar to find %t but found %t", tc.expected, found)
+			}
+		})
+	}
+}
+
+func TestAirstrikeTargets(t *testing.T) {
+
+	rules := RuleSets["classic"]
+	rules.Islands = []Coord{Coord{X: 4, Y: 4}}
+
+	tt := []struct {
+		name     string
+		start    Coord
+		fired    []Coord
+		expected []Coord
+	}{
+		{"When the row segment is open", Coord{X: 2, Y: 3}, nil, []Coord{Coord{X: 2, Y: 3}, Coord{X: 2, Y: 4}, Coord{X: 2, Y: 5}}},
+		{"When the segment runs off the board", Coord{X: 2, Y: 8}, nil, []Coord{Coord{X: 2, Y: 8}, Coord{X: 2, Y: 9}}},
+		{"When a location has been fired at", Coord{X: 2, Y: 3}, []Coord{Coord{X: 2, Y: 4}}, []Coord{Coord{X: 2, Y: 3}, Coord{X: 2, Y: 5}}},
+		{"When a location is an island", Coord{X: 4, Y: 3}, nil, []Coord{Coord{X: 4, Y: 3}, Coord{X: 4, Y: 5}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if targets := airstrikeTargets(rules, tc.start, tc.fired); !reflect.DeepEqual(targets, tc.expected) {
+				t.Fatalf("Expecting airstrike at %v but was %v", tc.expected, targets)
+			}
+		})
+	}
+}
+
+func TestTorpedoTarget(t *testing.T) {
+
+	rules := RuleSets["classic"]
+	rules.Islands = []Coord{Coord{X: 3, Y: 4}}
+	ships := []Ship{
+		Ship{Size: 3, Location: []Coord{Coord{X: 5, Y: 2, Hit: true}, Coord{X: 5, Y: 3}, Coord{X: 5, Y: 4}}},
+	}
+
+	tt := []struct {
+		name      string
+		start     Coord
+		direction Direction
+		expected  []Coord
+	}{
+		{"When the torpedo reaches a ship", Coord{X: 0, Y: 3}, DirectionDown, []Coord{Coord{X: 5, Y: 3}}},
+		{"When the torpedo passes a hole", Coord{X: 5, Y: 0}, DirectionRight, []Coord{Coord{X: 5, Y: 3}}},
+		{"When the torpedo leaves the board", Coord{X: 9, Y: 3}, DirectionLeft, nil},
+		{"When the torpedo runs aground on an island", Coord{X: 0, Y: 4}, DirectionDown, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if targets := torpedoTarget(rules, ships, tc.start, tc.direction); !reflect.DeepEqual(targets, tc.expected) {
+				t.Fatalf("Expecting torpedo at %v but was %v", tc.expected, targets)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+// CellState defines what a player knows about a location on a board
+type CellState int
+
+const (
+	// CellUnknown a location on the opponent's board which has not been fired at
+	CellUnknown CellState = 0
+
+	// CellEmpty a location on the player's own board with no ship which has not been fired at
+	CellEmpty CellState = 1
+
+	// CellShip a location on the player's own board with a ship which has not been hit
+	CellShip CellState = 2
+
+	// CellMiss a location which has been fired at and has no ship
+	CellMiss CellState = 3
+
+	// CellHit a location which has been fired at and has hit a ship that is still afloat
+	CellHit CellState = 4
+
+	// CellSunk a location of a ship which has been sunk
+	CellSunk CellState = 5
+
+	// CellIsland a location where ships cannot be placed. Shots at an island are wasted
+	CellIsland CellState = 6
+
+	// CellMine a mine on the player's own board or a mine on the opponent's board which has been fired at
+	CellMine CellState = 7
+)
+
+// newBoard creates a board of the given size with every location in the same state
+func newBoard(size int, state CellState) GameBoard {
+
+	var board GameBoard
+
+	for i := 0; i < size; i++ {
+		var row []Coord
+
+		for j := 0; j < size; j++ {
+			row = append(row, Coord{X: i, Y: j, State: state})
+		}
+
+		board.Coords = append(board.Coords, row)
+	}
+
+	return board
+}
+
+// cellAt returns the location on the board or nil if it is outside the board
+func (b GameBoard) cellAt(location Coord) *Coord {
+
+	if location.X < 0 || location.X >= len(b.Coords) {
+		return nil
+	}
+
+	if location.Y < 0 || location.Y >= len(b.Coords[location.X]) {
+		return nil
+	}
+
+	return &b.Coords[location.X][location.Y]
+}
+
+/*
+buildMyBoard constructs the player's view of their own board.
+Every ship is shown along with every shot the opponent has fired
+*/
+func buildMyBoard(size int, ships []Ship, opponentShots []Coord) GameBoard {
+
+	board := newBoard(size, CellEmpty)
+
+	for _, ship := range ships {
+		for _, location := range ship.Location {
+			if cell := board.cellAt(location); cell != nil {
+				cell.State = CellShip
+			}
+		}
+	}
+
+	markShots(board, ships, opponentShots)
+
+	return board
+}
+
+/*
+buildHitBoard constructs the player's view of the opponent's board.
+Only locations the player has fired at are revealed
+*/
+func buildHitBoard(size int, opponentShips []Ship, shots []Coord) GameBoard {
+
+	board := newBoard(size, CellUnknown)
+
+	markShots(board, opponentShips, shots)
+
+	return board
+}
+
+// markShots marks each shot on the board as a miss, a hit or part of a sunk ship
+func markShots(board GameBoard, ships []Ship, shots []Coord) {
+
+	for _, shot := range shots {
+
+		cell := board.cellAt(shot)
+		if cell == nil {
+			continue
+		}
+
+		cell.Hit = true
+		cell.State = CellMiss
+
+		shipIndex, _ := findShipAtLocation(ships, shot)
+		if shipIndex == -1 {
+			continue
+		}
+
+		if ships[shipIndex].Sunk {
+			cell.State = CellSunk
+		} else {
+			cell.State = CellHit
+		}
+	}
+}
+
+// markIslands marks every island on the board. Both players can see the islands from the start
+func markIslands(board GameBoard, islands []Coord) {
+	for _, island := range islands {
+		if cell := board.cellAt(island); cell != nil {
+			cell.State = CellIsland
+		}
+	}
+}
+
+// markMines marks each mine on the board. A mine which has been fired at keeps its Hit
+func markMines(board GameBoard, mines []Coord) {
+	for _, mine := range mines {
+		if cell := board.cellAt(mine); cell != nil {
+			cell.State = CellMine
+		}
+	}
+}
+
+// markRevealed shows the ship locations revealed by a mine on a board which has not fired at them yet
+func markRevealed(board GameBoard, revealed []Coord) {
+	for _, location := range revealed {
+		if cell := board.cellAt(location); cell != nil && cell.State == CellUnknown {
+			cell.State = CellShip
+		}
+	}
+}
+
+// minesFiredAt lists the mines which have been fired at
+func minesFiredAt(mines []Coord, shots []Coord) []Coord {
+
+	var result []Coord
+
+	for _, mine := range mines {
+		if containsCoord(shots, mine) {
+			result = append(result, mine)
+		}
+	}
+
+	return result
+}
+
+// containsCoord checks if the location is in the list ignoring Hit and State
+func containsCoord(locations []Coord, location Coord) bool {
+	for _, l := range locations {
+		if l.X == location.X && l.Y == location.Y {
+			return true
+		}
+	}
+
+	return false
+}
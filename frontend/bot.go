@@ -0,0 +1,408 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// BotDifficulty defines how a bot picks its shots
+type BotDifficulty string
+
+const (
+	// BotEasy fires at random locations
+	BotEasy BotDifficulty = "easy"
+
+	// BotMedium hunts at random until it hits a ship and then targets the locations around the hit
+	BotMedium BotDifficulty = "medium"
+
+	// BotHard fires at the location most likely to have a ship given every shot so far
+	BotHard BotDifficulty = "hard"
+)
+
+// BotStrategy picks the next shot from what the bot knows about the opponent's board
+type BotStrategy func(r *rand.Rand, board GameBoard, fleet [][]Coord) Coord
+
+// botStrategies maps each difficulty to the strategy used to pick shots
+var botStrategies = map[BotDifficulty]BotStrategy{
+	BotEasy:   randomShot,
+	BotMedium: huntTargetShot,
+	BotHard:   probabilityShot,
+}
+
+// newBotRandom creates the source of randomness for a bot's fleet or shot
+func newBotRandom() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// botDifficulties is the order bots of other difficulties fill the remaining seats of a game
+var botDifficulties = []BotDifficulty{BotEasy, BotMedium, BotHard}
+
+/*
+StartBotGame creates a game between the player and a bot of the difficulty. Games with more
+seats are filled with a bot of each of the other difficulties. The bots place their fleets
+straight away and play their turns through the same move pipeline as a person so every rule
+and clock applies to them
+*/
+func StartBotGame(db *sql.DB, cache *redis.Client, producer *kafka.Producer, difficulty BotDifficulty, ruleSet string, userID int) {
+
+	if _, ok := botStrategies[difficulty]; !ok {
+		PublishErrorEvent(producer, "Unknown bot difficulty", userID, 0)
+		return
+	}
+
+	rules, ok := FindRuleSet(ruleSet)
+	if !ok {
+		PublishErrorEvent(producer, "Unknown rule set", userID, 0)
+		return
+	}
+
+	difficulties := botsForGame(difficulty, rules.seats()-1)
+	if difficulties == nil {
+		PublishErrorEvent(producer, "There are not enough bots to fill the game", userID, 0)
+		return
+	}
+
+	players := []int{userID}
+	for _, botDifficulty := range difficulties {
+		botID, err := FindOrCreateBotUser(db, botDifficulty)
+
+		if err != nil {
+			PublishErrorEvent(producer, err.Error(), userID, 0)
+			return
+		}
+
+		players = append(players, botID)
+	}
+
+	gameID, err := createGame(db, players, rules.Name, 0, true)
+	if err != nil {
+		PublishErrorEvent(producer, "Could not create game", userID, 0)
+		return
+	}
+
+	PublishGameStarted(db, producer, gameID)
+
+	r := newBotRandom()
+
+	for _, botID := range players[1:] {
+		ships := RandomFleet(r, rules)
+		if ships == nil {
+			PublishErrorEvent(producer, "Bot could not place its ships", userID, gameID)
+			return
+		}
+
+		placeFleet(db, producer, gameID, botID, ships, RandomMines(r, rules, ships))
+	}
+}
+
+// botsForGame picks the difficulty of each bot in a game, starting with the difficulty asked for.
+// Each bot can only take one seat so nil is returned if there are not enough difficulties
+func botsForGame(difficulty BotDifficulty, count int) []BotDifficulty {
+
+	if count > len(botDifficulties) {
+		return nil
+	}
+
+	bots := []BotDifficulty{difficulty}
+	for _, other := range botDifficulties {
+		if len(bots) < count && other != difficulty {
+			bots = append(bots, other)
+		}
+	}
+
+	return bots[:count]
+}
+
+// playBotTurn makes the move for a bot if it is the bot's turn in the game
+func playBotTurn(db *sql.DB, producer *kafka.Producer, gameID int) {
+
+	status, _ := FindGameState(db, gameID)
+	if status != "Started" {
+		return
+	}
+
+	botID := FindTurnForGame(db, gameID)
+	if botID == -1 {
+		return
+	}
+
+	strategy, ok := botStrategies[FindBotDifficulty(db, botID)]
+	if !ok {
+		return
+	}
+
+	// The bot only sees what a person would see
+	update := ConstructGameUpdateMessage(db, gameID, botID)
+	targets := botVolley(newBotRandom(), strategy, update.HitBoard, FindRuleSetForGame(db, gameID).fleetShapes(), update.Shots)
+
+	if len(targets) == 0 {
+		log.Printf("Bot %d has nowhere to fire in game %d", botID, gameID)
+		return
+	}
+
+	// Bots always fire at the next player in the turn order
+	makeMove(db, producer, gameID, targets, 0, botID)
+}
+
+// botVolley picks the shots for a turn. Each shot is treated as a miss while picking
+// the rest of the volley so the same location is not picked twice
+func botVolley(r *rand.Rand, strategy BotStrategy, board GameBoard, fleet [][]Coord, shots int) []Coord {
+
+	var targets []Coord
+
+	for len(targets) < shots {
+		target := strategy(r, board, fleet)
+		if target.X == -1 {
+			break
+		}
+
+		targets = append(targets, target)
+		board.cellAt(target).State = CellMiss
+	}
+
+	return targets
+}
+
+// randomShot fires at any location that has not been fired at
+func randomShot(r *rand.Rand, board GameBoard, fleet [][]Coord) Coord {
+	return pickRandom(r, unknownCells(board, nil))
+}
+
+/*
+huntTargetShot fires at ship locations given away by mines and next to ships that have been
+hit but not sunk. When two hits are in a line it keeps firing along the line. With nothing to
+target it hunts on a checkerboard as every ship covers at least one of those locations
+*/
+func huntTargetShot(r *rand.Rand, board GameBoard, fleet [][]Coord) Coord {
+
+	revealed := unknownCells(board, func(c Coord) bool { return board.cellAt(c).State == CellShip })
+	if len(revealed) > 0 {
+		return pickRandom(r, revealed)
+	}
+
+	var lineTargets, targets []Coord
+
+	for _, row := range board.Coords {
+		for _, cell := range row {
+			if cell.State != CellHit {
+				continue
+			}
+
+			for _, direction := range []Coord{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}} {
+				next := Coord{X: cell.X + direction.X, Y: cell.Y + direction.Y}
+				if !isUnknown(board, next) {
+					continue
+				}
+
+				targets = append(targets, next)
+
+				// Prefer continuing a line of hits
+				behind := board.cellAt(Coord{X: cell.X - direction.X, Y: cell.Y - direction.Y})
+				if behind != nil && behind.State == CellHit {
+					lineTargets = append(lineTargets, next)
+				}
+			}
+		}
+	}
+
+	if len(lineTargets) > 0 {
+		return pickRandom(r, lineTargets)
+	}
+
+	if len(targets) > 0 {
+		return pickRandom(r, targets)
+	}
+
+	checkerboard := unknownCells(board, func(c Coord) bool { return (c.X+c.Y)%2 == 0 })
+	if len(checkerboard) > 0 {
+		return pickRandom(r, checkerboard)
+	}
+
+	return randomShot(r, board, fleet)
+}
+
+/*
+probabilityShot counts every way the ships still afloat could lie on the board, in every
+rotation and reflection of their shape, without crossing a miss, a sunk ship, an island or a
+mine. Placements that cover hits or ship locations given away by mines are weighted heavily
+so the bot finishes off damaged ships. It fires at the location covered by the most placements
+*/
+func probabilityShot(r *rand.Rand, board GameBoard, fleet [][]Coord) Coord {
+
+	const hitWeight = 20
+
+	size := len(board.Coords)
+	weights := make(map[Coord]int)
+
+	for _, shape := range remainingShips(board, fleet) {
+		for _, orientation := range orientations(shape) {
+			for x := 0; x < size; x++ {
+				for y := 0; y < size; y++ {
+
+					var cells []Coord
+					blocked := false
+					hits := 0
+
+					for i := 0; i < len(orientation) && !blocked; i++ {
+						cell := board.cellAt(Coord{X: x + orientation[i].X, Y: y + orientation[i].Y})
+
+						if cell == nil {
+							blocked = true
+						} else if cell.State == CellHit {
+							hits++
+						} else if cell.State == CellShip {
+							hits++
+							cells = append(cells, Coord{X: cell.X, Y: cell.Y})
+						} else if cell.State == CellUnknown {
+							cells = append(cells, Coord{X: cell.X, Y: cell.Y})
+						} else {
+							blocked = true
+						}
+					}
+
+					if blocked {
+						continue
+					}
+
+					for _, cell := range cells {
+						weights[cell] += 1 + hits*hitWeight
+					}
+				}
+			}
+		}
+	}
+
+	var best []Coord
+	bestWeight := 0
+
+	for cell, weight := range weights {
+		if weight > bestWeight {
+			best = []Coord{cell}
+			bestWeight = weight
+		} else if weight == bestWeight {
+			best = append(best, cell)
+		}
+	}
+
+	if len(best) == 0 {
+		return randomShot(r, board, fleet)
+	}
+
+	// Map order is random so sort the ties to keep a seeded bot repeatable
+	sortCoords(best)
+
+	return pickRandom(r, best)
+}
+
+/*
+remainingShips removes the ships that have been sunk from the fleet. Sunk ships are found
+as groups of touching sunk locations, a group that does not have the shape of a ship left in
+the fleet, such as two sunk ships side by side, is left alone
+*/
+func remainingShips(board GameBoard, fleet [][]Coord) [][]Coord {
+
+	remaining := append([][]Coord(nil), fleet...)
+	seen := make(map[Coord]bool)
+
+	for _, row := range board.Coords {
+		for _, cell := range row {
+			start := Coord{X: cell.X, Y: cell.Y}
+			if cell.State != CellSunk || seen[start] {
+				continue
+			}
+
+			sunk := canonicalShape(sunkGroup(board, start, seen))
+
+			for i, shape := range remaining {
+				if canonicalShape(shape) == sunk {
+					remaining = append(remaining[:i], remaining[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	return remaining
+}
+
+// sunkGroup flood fills the sunk locations touching the location, marking each one as seen
+func sunkGroup(board GameBoard, start Coord, seen map[Coord]bool) []Coord {
+
+	var group []Coord
+
+	seen[start] = true
+	pending := []Coord{start}
+
+	for len(pending) > 0 {
+		location := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		group = append(group, location)
+
+		for _, direction := range []Coord{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}} {
+			next := Coord{X: location.X + direction.X, Y: location.Y + direction.Y}
+			cell := board.cellAt(next)
+
+			if cell != nil && cell.State == CellSunk && !seen[next] {
+				seen[next] = true
+				pending = append(pending, next)
+			}
+		}
+	}
+
+	return group
+}
+
+// unknownCells lists the locations which have not been fired at and match the filter
+func unknownCells(board GameBoard, filter func(Coord) bool) []Coord {
+
+	var cells []Coord
+
+	for _, row := range board.Coords {
+		for _, cell := range row {
+			location := Coord{X: cell.X, Y: cell.Y}
+			if notFiredAt(cell.State) && (filter == nil || filter(location)) {
+				cells = append(cells, location)
+			}
+		}
+	}
+
+	return cells
+}
+
+// isUnknown checks if the location is on the board and has not been fired at
+func isUnknown(board GameBoard, location Coord) bool {
+	cell := board.cellAt(location)
+	return cell != nil && notFiredAt(cell.State)
+}
+
+// notFiredAt checks if a location on the opponent's board is still worth firing at.
+// Ship locations given away by mines have not been fired at yet
+func notFiredAt(state CellState) bool {
+	return state == CellUnknown || state == CellShip
+}
+
+// pickRandom picks one of the locations. Returns (-1, -1) if there are none
+func pickRandom(r *rand.Rand, cells []Coord) Coord {
+
+	if len(cells) == 0 {
+		return Coord{X: -1, Y: -1}
+	}
+
+	return cells[r.Intn(len(cells))]
+}
+
+// sortCoords orders locations by row and then column
+func sortCoords(cells []Coord) {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].X != cells[j].X {
+			return cells[i].X < cells[j].X
+		}
+		return cells[i].Y < cells[j].Y
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBotStrategies(t *testing.T) {
+
+	for _, ruleSet := range []string{"classic", "shapes"} {
+		for difficulty, strategy := range botStrategies {
+			testBotStrategy(t, RuleSets[ruleSet], difficulty, strategy)
+		}
+	}
+}
+
+func testBotStrategy(t *testing.T, rules RuleSet, difficulty BotDifficulty, strategy BotStrategy) {
+
+	t.Run(rules.Name+" "+string(difficulty), func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		ships := RandomFleet(r, rules)
+
+		var shots []Coord
+		fired := make(map[Coord]bool)
+
+		for !areAllShipsSunk(ships) {
+			if len(shots) == rules.BoardSize*rules.BoardSize {
+				t.Fatalf("Expecting the bot to sink every ship within %d shots", len(shots))
+			}
+
+			board := buildHitBoard(rules.BoardSize, ships, shots)
+			target := strategy(r, board, rules.fleetShapes())
+
+			if !rules.isOnBoard(target) {
+				t.Fatalf("Expecting the bot to fire on the board but fired at (%d, %d)", target.X, target.Y)
+			}
+
+			if fired[target] {
+				t.Fatalf("Expecting the bot not to fire at (%d, %d) twice", target.X, target.Y)
+			}
+
+			fired[target] = true
+			shots = append(shots, target)
+			resolveShot(ships, target)
+		}
+	})
+}
+
+func TestRemainingShips(t *testing.T) {
+
+	fleet := RuleSets["shapes"].fleetShapes()
+
+	tt := []struct {
+		name     string
+		sunk     []Coord
+		expected int
+	}{
+		{"When no ships have been sunk", nil, 4},
+		{"When a straight ship has been sunk", []Coord{{X: 0, Y: 0}, {X: 0, Y: 1}}, 3},
+		{"When an L shaped ship has been sunk", []Coord{{X: 5, Y: 5}, {X: 5, Y: 6}, {X: 5, Y: 7}, {X: 6, Y: 5}}, 3},
+		{"When a T shaped ship has been sunk", []Coord{{X: 3, Y: 3}, {X: 4, Y: 3}, {X: 5, Y: 3}, {X: 4, Y: 4}}, 3},
+		{"When sunk ships touch", []Coord{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}, 4},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			board := newBoard(10, CellUnknown)
+			for _, location := range tc.sunk {
+				board.cellAt(location).State = CellSunk
+			}
+
+			if remaining := remainingShips(board, fleet); len(remaining) != tc.expected {
+				t.Fatalf("Expecting %d ships afloat but was %d", tc.expected, len(remaining))
+			}
+		})
+	}
+}
+
+func TestBotVolley(t *testing.T) {
+
+	rules := RuleSets["salvo"]
+	r := rand.New(rand.NewSource(1))
+	ships := RandomFleet(r, rules)
+	board := buildHitBoard(rules.BoardSize, ships, nil)
+
+	targets := botVolley(r, huntTargetShot, board, rules.fleetShapes(), 5)
+
+	if len(targets) != 5 {
+		t.Fatalf("Expecting the bot to fire 5 shots but fired %d", len(targets))
+	}
+
+	fired := make(map[Coord]bool)
+	for _, target := range targets {
+		if fired[target] {
+			t.Fatalf("Expecting the bot not to fire at (%d, %d) twice in a volley", target.X, target.Y)
+		}
+		fired[target] = true
+	}
+}
+
+func TestBotsForGame(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		difficulty BotDifficulty
+		count      int
+		expected   []BotDifficulty
+	}{
+		{"When one bot is needed", BotHard, 1, []BotDifficulty{BotHard}},
+		{"When two bots are needed", BotMedium, 2, []BotDifficulty{BotMedium, BotEasy}},
+		{"When three bots are needed", BotHard, 3, []BotDifficulty{BotHard, BotEasy, BotMedium}},
+		{"When there are not enough bots", BotEasy, 4, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if bots := botsForGame(tc.difficulty, tc.count); !reflect.DeepEqual(bots, tc.expected) {
+				t.Fatalf("Expecting bots %v but was %v", tc.expected, bots)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"sort"
+)
+
+// TournamentFormat decides how the players of a tournament are paired each round
+type TournamentFormat string
+
+const (
+	// SingleElimination knocks a player out of the tournament on their first loss
+	SingleElimination TournamentFormat = "single"
+
+	// DoubleElimination knocks a player out on their second loss. Players who lose once
+	// play on against the others with one loss until they meet the unbeaten player in the final
+	DoubleElimination TournamentFormat = "double"
+
+	// Swiss plays a fixed number of rounds, pairing players with the same number of wins
+	Swiss TournamentFormat = "swiss"
+)
+
+// TournamentPlayer is a player signed up to a tournament. The best player is seeded 1
+type TournamentPlayer struct {
+	PlayerID int
+	Seed     int
+}
+
+// TournamentMatch is a game between two players in a round of a tournament. A match
+// without a Player2 is a bye, won by Player1 without playing. Forfeit is set when the
+// winner went through because the other player did not show up
+type TournamentMatch struct {
+	ID      int
+	Round   int
+	Slot    int
+	Player1 int
+	Player2 int
+	GameID  int
+	Winner  int
+	Forfeit bool
+}
+
+// Standing is how a player is doing in a tournament. Buchholz is the sum of the
+// wins of every opponent the player has met, used to break ties in Swiss tournaments
+type Standing struct {
+	PlayerID  int
+	Seed      int
+	Wins      int
+	Losses    int
+	Byes      int
+	Buchholz  int
+	Opponents []int
+}
+
+// maxLosses is how many losses knock a player out of an elimination tournament
+func (f TournamentFormat) maxLosses() int {
+
+	if f == DoubleElimination {
+		return 2
+	}
+
+	return 1
+}
+
+// isValid checks the format is one the server can run
+func (f TournamentFormat) isValid() bool {
+	return f == SingleElimination || f == DoubleElimination || f == Swiss
+}
+
+// standings works out the wins and losses of every player from the matches
+// played so far. The standings are in seed order
+func standings(players []TournamentPlayer, matches []TournamentMatch) []Standing {
+
+	var result []Standing
+	index := make(map[int]int)
+
+	for _, player := range players {
+		index[player.PlayerID] = len(result)
+		result = append(result, Standing{PlayerID: player.PlayerID, Seed: player.Seed})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Seed < result[j].Seed })
+	for i, standing := range result {
+		index[standing.PlayerID] = i
+	}
+
+	for _, match := range matches {
+		if match.Winner == 0 {
+			continue
+		}
+
+		winner := &result[index[match.Winner]]
+		winner.Wins++
+
+		if match.Player2 == 0 {
+			winner.Byes++
+			continue
+		}
+
+		loserID := match.Player1
+		if loserID == match.Winner {
+			loserID = match.Player2
+		}
+
+		loser := &result[index[loserID]]
+		loser.Losses++
+
+		winner.Opponents = append(winner.Opponents, loserID)
+		loser.Opponents = append(loser.Opponents, match.Winner)
+	}
+
+	for i := range result {
+		for _, opponentID := range result[i].Opponents {
+			result[i].Buchholz += result[index[opponentID]].Wins
+		}
+	}
+
+	return result
+}
+
+// roundComplete checks every match of the round has a winner. There is nothing to play
+// before the first round, but any later round without matches has not been set up
+func roundComplete(matches []TournamentMatch, round int) bool {
+
+	if round == 0 {
+		return true
+	}
+
+	played := false
+
+	for _, match := range matches {
+		if match.Round != round {
+			continue
+		}
+
+		if match.Winner == 0 {
+			return false
+		}
+
+		played = true
+	}
+
+	return played
+}
+
+/*
+nextRound pairs the players for the round after the current one. Byes are returned as
+matches already won by Player1. Once the tournament is decided no matches are returned
+and the winner is given instead. Rounds is only used by Swiss tournaments
+*/
+func nextRound(format TournamentFormat, rounds int, round int, players []TournamentPlayer, matches []TournamentMatch) ([]TournamentMatch, int) {
+
+	table := standings(players, matches)
+
+	var pairs [][2]int
+	var winner int
+
+	if format == Swiss {
+		pairs, winner = swissPairs(table, rounds, round)
+	} else {
+		pairs, winner = eliminationPairs(table, format.maxLosses(), round, matches)
+	}
+
+	var result []TournamentMatch
+	for slot, pair := range pairs {
+		match := TournamentMatch{Round: round + 1, Slot: slot, Player1: pair[0], Player2: pair[1]}
+		if pair[1] == 0 {
+			match.Winner = pair[0]
+		}
+
+		result = append(result, match)
+	}
+
+	return result, winner
+}
+
+/*
+eliminationPairs pairs the players who are still in. The first round follows the seeded
+bracket so the top seeds get any byes and only meet late on. After that players are
+paired with others on the same number of losses in bracket order, so in a single
+elimination tournament the winners of neighbouring matches meet. A player alone on their
+number of losses waits for the next round, until only two players are left for the final
+*/
+func eliminationPairs(table []Standing, maxLosses int, round int, matches []TournamentMatch) ([][2]int, int) {
+
+	var alive []Standing
+	for _, standing := range table {
+		if standing.Losses < maxLosses {
+			alive = append(alive, standing)
+		}
+	}
+
+	if len(alive) == 0 {
+		return nil, 0
+	}
+
+	if len(alive) == 1 {
+		return nil, alive[0].PlayerID
+	}
+
+	if round == 0 {
+		return bracketPairs(alive), 0
+	}
+
+	if len(alive) == 2 {
+		return [][2]int{{alive[0].PlayerID, alive[1].PlayerID}}, 0
+	}
+
+	// Keep the order of the bracket from the last round. Players who sat it out go last
+	slots := make(map[int]int)
+	for _, match := range matches {
+		if match.Round == round {
+			slots[match.Player1] = match.Slot + 1
+			slots[match.Player2] = match.Slot + 1
+		}
+	}
+
+	order := func(s Standing) int {
+		if slots[s.PlayerID] == 0 {
+			return len(matches) + s.Seed
+		}
+
+		return slots[s.PlayerID]
+	}
+
+	var pairs [][2]int
+	for losses := 0; losses < maxLosses; losses++ {
+		var group []Standing
+		for _, standing := range alive {
+			if standing.Losses == losses {
+				group = append(group, standing)
+			}
+		}
+
+		if len(group) < 2 {
+			continue
+		}
+
+		sort.SliceStable(group, func(i, j int) bool { return order(group[i]) < order(group[j]) })
+
+		// The best seed in an odd group gets the bye
+		if len(group)%2 == 1 {
+			best := 0
+			for i := range group {
+				if group[i].Seed < group[best].Seed {
+					best = i
+				}
+			}
+
+			pairs = append(pairs, [2]int{group[best].PlayerID, 0})
+			group = append(group[:best:best], group[best+1:]...)
+		}
+
+		for i := 0; i+1 < len(group); i += 2 {
+			pairs = append(pairs, [2]int{group[i].PlayerID, group[i+1].PlayerID})
+		}
+	}
+
+	return pairs, 0
+}
+
+// bracketPairs pairs the players in seed order into a full bracket. Places in the
+// bracket without a player are byes for the seed they would have played
+func bracketPairs(players []Standing) [][2]int {
+
+	size := 1
+	for size < len(players) {
+		size *= 2
+	}
+
+	seed := func(n int) int {
+		if n > len(players) {
+			return 0
+		}
+
+		return players[n-1].PlayerID
+	}
+
+	order := bracketOrder(size)
+
+	var pairs [][2]int
+	for i := 0; i+1 < len(order); i += 2 {
+		pairs = append(pairs, [2]int{seed(order[i]), seed(order[i+1])})
+	}
+
+	return pairs
+}
+
+// bracketOrder lists the seeds of a bracket of the size from top to bottom. Each pair meets
+// in the first round and the top two seeds can only meet in the final
+func bracketOrder(size int) []int {
+
+	order := []int{1}
+	for len(order) < size {
+		var next []int
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)+1-seed)
+		}
+
+		order = next
+	}
+
+	return order
+}
+
+/*
+swissPairs pairs the players ranked by wins so players on the same score meet, avoiding
+rematches where possible. With an odd number of players the lowest ranked player who
+has not had a bye yet sits the round out and is given the win. Once every round has been
+played the top ranked player wins. With no number of rounds set there are enough rounds
+to leave a single unbeaten player
+*/
+func swissPairs(table []Standing, rounds int, round int) ([][2]int, int) {
+
+	if len(table) == 0 {
+		return nil, 0
+	}
+
+	if rounds <= 0 {
+		rounds = swissRounds(len(table))
+	}
+
+	ranked := swissRanking(table)
+
+	if round >= rounds {
+		return nil, ranked[0].PlayerID
+	}
+
+	var pairs [][2]int
+
+	if len(ranked)%2 == 1 {
+		bye := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if ranked[i].Byes == 0 {
+				bye = i
+				break
+			}
+		}
+
+		pairs = append(pairs, [2]int{ranked[bye].PlayerID, 0})
+		ranked = append(ranked[:bye:bye], ranked[bye+1:]...)
+	}
+
+	paired := make([]bool, len(ranked))
+	for i := range ranked {
+		if paired[i] {
+			continue
+		}
+
+		opponent := -1
+		for j := i + 1; j < len(ranked); j++ {
+			if paired[j] {
+				continue
+			}
+
+			if opponent == -1 {
+				opponent = j
+			}
+
+			if !containsInt(ranked[i].Opponents, ranked[j].PlayerID) {
+				opponent = j
+				break
+			}
+		}
+
+		paired[i] = true
+		paired[opponent] = true
+		pairs = append(pairs, [2]int{ranked[i].PlayerID, ranked[opponent].PlayerID})
+	}
+
+	return pairs, 0
+}
+
+// swissRanking orders the standings by wins, then Buchholz, then seed
+func swissRanking(table []Standing) []Standing {
+
+	ranked := append([]Standing(nil), table...)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Wins != ranked[j].Wins {
+			return ranked[i].Wins > ranked[j].Wins
+		}
+
+		if ranked[i].Buchholz != ranked[j].Buchholz {
+			return ranked[i].Buchholz > ranked[j].Buchholz
+		}
+
+		return ranked[i].Seed < ranked[j].Seed
+	})
+
+	return ranked
+}
+
+// swissRounds is how many rounds it takes to leave one unbeaten player out of the players
+func swissRounds(players int) int {
+
+	rounds := 0
+	for 1<<uint(rounds) < players {
+		rounds++
+	}
+
+	return rounds
+}
+
+func containsInt(values []int, value int) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
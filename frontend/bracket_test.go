@@ -0,0 +1,215 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBracketOrder(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		size     int
+		expected []int
+	}{
+		{"When there are two players", 2, []int{1, 2}},
+		{"When there are four players", 4, []int{1, 4, 2, 3}},
+		{"When there are eight players", 8, []int{1, 8, 4, 5, 2, 7, 3, 6}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if order := bracketOrder(tc.size); !reflect.DeepEqual(order, tc.expected) {
+				t.Fatalf("Expecting bracket %v but was %v", tc.expected, order)
+			}
+		})
+	}
+}
+
+func TestNextRoundFirstRound(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		format   TournamentFormat
+		players  int
+		expected [][2]int
+	}{
+		{"When the bracket is full", SingleElimination, 4, [][2]int{{1, 4}, {2, 3}}},
+		{"When the top seeds get byes", SingleElimination, 6, [][2]int{{1, 0}, {4, 5}, {2, 0}, {3, 6}}},
+		{"When a double elimination bracket has a bye", DoubleElimination, 3, [][2]int{{1, 0}, {2, 3}}},
+		{"When a Swiss round has a bye", Swiss, 5, [][2]int{{5, 0}, {1, 2}, {3, 4}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, winner := nextRound(tc.format, 0, 0, seededPlayers(tc.players), nil)
+
+			if winner != 0 {
+				t.Fatalf("Expecting no winner before the first round but was %d", winner)
+			}
+
+			var pairs [][2]int
+			for _, match := range matches {
+				pairs = append(pairs, [2]int{match.Player1, match.Player2})
+
+				if match.Player2 == 0 && match.Winner != match.Player1 {
+					t.Fatalf("Expecting player %d to win their bye", match.Player1)
+				}
+			}
+
+			if !reflect.DeepEqual(pairs, tc.expected) {
+				t.Fatalf("Expecting pairs %v but was %v", tc.expected, pairs)
+			}
+		})
+	}
+}
+
+func TestPlayTournament(t *testing.T) {
+
+	higherSeed := func(player1 int, player2 int) int {
+		if player1 < player2 {
+			return player1
+		}
+		return player2
+	}
+
+	lowerSeed := func(player1 int, player2 int) int {
+		if player1 > player2 {
+			return player1
+		}
+		return player2
+	}
+
+	tt := []struct {
+		name      string
+		format    TournamentFormat
+		rounds    int
+		players   int
+		winnerOf  func(int, int) int
+		expected  int
+		maxLosses int
+	}{
+		{"When the favourite wins a single elimination", SingleElimination, 0, 8, higherSeed, 1, 1},
+		{"When the underdog wins a single elimination", SingleElimination, 0, 5, lowerSeed, 5, 1},
+		{"When the favourite wins a double elimination", DoubleElimination, 0, 8, higherSeed, 1, 2},
+		{"When the underdog wins a double elimination", DoubleElimination, 0, 6, lowerSeed, 6, 2},
+		{"When the favourite wins a Swiss tournament", Swiss, 0, 8, higherSeed, 1, 3},
+		{"When a Swiss tournament has a set number of rounds", Swiss, 4, 7, higherSeed, 1, 4},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			players := seededPlayers(tc.players)
+
+			var matches []TournamentMatch
+			winner := 0
+
+			for round := 0; winner == 0; round++ {
+				if round > 3*tc.players {
+					t.Fatalf("Expecting the tournament to finish within %d rounds", round)
+				}
+
+				var next []TournamentMatch
+				next, winner = nextRound(tc.format, tc.rounds, round, players, matches)
+
+				if winner == 0 && len(next) == 0 {
+					t.Fatalf("Expecting matches in round %d", round+1)
+				}
+
+				met := make(map[int]bool)
+				for _, match := range next {
+					for _, playerID := range []int{match.Player1, match.Player2} {
+						if met[playerID] {
+							t.Fatalf("Expecting player %d to play once in round %d", playerID, round+1)
+						}
+
+						if playerID != 0 {
+							met[playerID] = true
+						}
+					}
+
+					if match.Player2 != 0 {
+						match.Winner = tc.winnerOf(match.Player1, match.Player2)
+					}
+
+					matches = append(matches, match)
+				}
+			}
+
+			if winner != tc.expected {
+				t.Fatalf("Expecting player %d to win but was %d", tc.expected, winner)
+			}
+
+			for _, standing := range standings(players, matches) {
+				if standing.Losses > tc.maxLosses {
+					t.Fatalf("Expecting player %d to lose at most %d times but lost %d", standing.PlayerID, tc.maxLosses, standing.Losses)
+				}
+			}
+		})
+	}
+}
+
+func TestSwissPairsAvoidRematches(t *testing.T) {
+
+	players := seededPlayers(4)
+	matches := []TournamentMatch{
+		{Round: 1, Slot: 0, Player1: 1, Player2: 2, Winner: 1},
+		{Round: 1, Slot: 1, Player1: 3, Player2: 4, Winner: 3},
+		{Round: 2, Slot: 0, Player1: 1, Player2: 3, Winner: 1},
+		{Round: 2, Slot: 1, Player1: 2, Player2: 4, Winner: 2},
+	}
+
+	next, winner := nextRound(Swiss, 3, 2, players, matches)
+
+	if winner != 0 {
+		t.Fatalf("Expecting a third round but player %d won", winner)
+	}
+
+	expected := [][2]int{{1, 4}, {2, 3}}
+
+	var pairs [][2]int
+	for _, match := range next {
+		pairs = append(pairs, [2]int{match.Player1, match.Player2})
+	}
+
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Fatalf("Expecting pairs %v but was %v", expected, pairs)
+	}
+}
+
+func seededPlayers(count int) (players []TournamentPlayer) {
+
+	for i := 1; i <= count; i++ {
+		players = append(players, TournamentPlayer{PlayerID: i, Seed: i})
+	}
+
+	return players
+}
+
+func TestRoundComplete(t *testing.T) {
+
+	matches := []TournamentMatch{
+		{Round: 1, Slot: 0, Player1: 1, Player2: 4, Winner: 1},
+		{Round: 1, Slot: 1, Player1: 2, Player2: 3, Winner: 3},
+		{Round: 2, Slot: 0, Player1: 1, Player2: 3},
+	}
+
+	tt := []struct {
+		name     string
+		round    int
+		expected bool
+	}{
+		{"When the first round has not started", 0, true},
+		{"When every match of the round has a winner", 1, true},
+		{"When a match of the round is still being played", 2, false},
+		{"When the round has no matches", 3, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if complete := roundComplete(matches, tc.round); complete != tc.expected {
+				t.Fatalf("Expecting round %d complete to be %v but was %v", tc.round, tc.expected, complete)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -12,6 +15,9 @@ import (
 // SessionTime defines the time to session timeout
 const SessionTime = time.Minute * 60
 
+// RememberSessionTime defines the time to session timeout when the user asks to be remembered
+const RememberSessionTime = time.Hour * 24 * 30
+
 // ConnectCache Connects to the cache (redis)
 func ConnectCache() *redis.Client {
 	client := redis.NewClient(&redis.Options{
@@ -28,10 +34,10 @@ func ConnectCache() *redis.Client {
 
 }
 
-// GenerateSession creates a UUID for the session and returns
-func GenerateSession(client *redis.Client, userID int) string {
+// GenerateSession creates a UUID for the session lasting the session time and returns
+func GenerateSession(client *redis.Client, userID int, sessionTime time.Duration) string {
 	sessionID := uuid.New().String()
-	client.Set("Session-"+sessionID, userID, SessionTime)
+	client.Set("Session-"+sessionID, userID, sessionTime)
 	return sessionID
 }
 
@@ -48,19 +54,352 @@ func CheckSession(client *redis.Client, sessionID string) (int, error) {
 	return i, err2
 }
 
-// CheckIfSomeOneIsWaiting checks if someone is waiting for connection
-func CheckIfSomeOneIsWaiting(client *redis.Client) int {
-	userID, err := client.LPop("WaitingQueue").Result()
+// waitingQueue is the queue of players waiting for a game with the rule set and days for a move.
+// Live games have no days for a move
+func waitingQueue(ruleSet string, moveDays int) string {
+
+	queue := "WaitingQueue"
+	if ruleSet != DefaultRuleSet {
+		queue += "-" + ruleSet
+	}
+
+	if moveDays > 0 {
+		queue += "-" + strconv.Itoa(moveDays) + "Days"
+	}
+
+	return queue
+}
+
+/*
+FindPlayersWaiting takes the first count players other than the user out of the queue for the
+rule set and days for a move. Nobody is taken out unless there are enough of them waiting, in which case nil is
+returned. The user is taken out of whichever queue they joined too when they are found, as they
+are about to be seated. The queue is watched so players taken by another server at the same
+time are never seated twice
+*/
+func FindPlayersWaiting(client *redis.Client, ruleSet string, moveDays int, count int, userID int) []int {
+
+	queue := waitingQueue(ruleSet, moveDays)
+	self := strconv.Itoa(userID)
+	var members []string
+
+	err := client.Watch(func(tx *redis.Tx) error {
+		waiting, err := tx.LRange(queue, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		var others []string
+		for _, member := range waiting {
+			if member != self && len(others) < count {
+				others = append(others, member)
+			}
+		}
+
+		if len(others) < count {
+			return nil
+		}
+
+		// The user may be waiting for a different game than the one they are being seated in
+		ruleSet, days := waitingFor(tx, userID)
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			for _, member := range others {
+				dequeue(pipe, queue, member)
+			}
+
+			pipe.LRem(queue, 0, self)
+			dequeue(pipe, waitingQueue(ruleSet, days), self)
+			return nil
+		})
+
+		if err == nil {
+			members = others
+		}
+
+		return err
+	}, queue)
+
+	if err != nil || members == nil {
+		return nil
+	}
+
+	var userIDs []int
+	for _, member := range members {
+		userID, _ := strconv.Atoi(member)
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs
+}
+
+/*
+AddToEndOfQueue adds the current user to the end of the queue for the rule set and days for a
+move. A user already waiting is taken out first so they are only queued once. Only players
+waiting for a live game are timed, players waiting for a correspondence game wait for another player
+*/
+func AddToEndOfQueue(client *redis.Client, ruleSet string, moveDays int, userID int) {
+	RemoveFromQueue(client, userID)
+
+	if moveDays == 0 {
+		client.ZAdd("WaitingSince", redis.Z{Score: float64(time.Now().Unix()), Member: userID})
+	}
+
+	client.HSet("WaitingRuleSet", strconv.Itoa(userID), ruleSet)
+	client.HSet("WaitingMoveDays", strconv.Itoa(userID), moveDays)
+	client.RPush(waitingQueue(ruleSet, moveDays), userID)
+}
+
+// FindUsersWaitingSince finds the users who have been in the queue since before the time
+func FindUsersWaitingSince(client *redis.Client, since time.Time) []int {
+
+	members, err := client.ZRangeByScore("WaitingSince", redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(since.Unix(), 10),
+	}).Result()
+
+	if err != nil {
+		return nil
+	}
+
+	var userIDs []int
+	for _, member := range members {
+		if userID, err := strconv.Atoi(member); err == nil {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return userIDs
+}
+
+// RemoveFromQueue takes the user out of the queue and returns the rule set and days for a move
+// they were waiting for. Returns false if they had already left it
+func RemoveFromQueue(client *redis.Client, userID int) (string, int, bool) {
+
+	ruleSet, moveDays := waitingFor(client, userID)
+
+	client.ZRem("WaitingSince", userID)
+	client.HDel("WaitingRuleSet", strconv.Itoa(userID))
+	client.HDel("WaitingMoveDays", strconv.Itoa(userID))
+
+	count, err := client.LRem(waitingQueue(ruleSet, moveDays), 0, userID).Result()
+	return ruleSet, moveDays, err == nil && count > 0
+}
+
+// waitingFor finds the rule set and days for a move the user joined the queue for
+func waitingFor(client redis.Cmdable, userID int) (string, int) {
+
+	ruleSet, err := client.HGet("WaitingRuleSet", strconv.Itoa(userID)).Result()
+	if err != nil {
+		ruleSet = DefaultRuleSet
+	}
+
+	// Players queued before there were correspondence games are waiting for a live game
+	moveDays, err := client.HGet("WaitingMoveDays", strconv.Itoa(userID)).Int()
+	if err != nil {
+		moveDays = 0
+	}
+
+	return ruleSet, moveDays
+}
+
+// dequeue takes the member out of the queue and forgets what they were waiting for
+func dequeue(pipe redis.Pipeliner, queue string, member string) {
+	pipe.LRem(queue, 0, member)
+	pipe.ZRem("WaitingSince", member)
+	pipe.HDel("WaitingRuleSet", member)
+	pipe.HDel("WaitingMoveDays", member)
+}
+
+// RematchOfferTime defines how long a rematch offer stays open
+const RematchOfferTime = time.Minute * 5
+
+// OfferRematch stores a rematch offer from the player for the game
+func OfferRematch(client *redis.Client, gameID int, userID int) {
+	client.Set("Rematch-"+strconv.Itoa(gameID), userID, RematchOfferTime)
+}
+
+// FindRematchOffer finds the player who offered a rematch for the game. Returns -1 if there is no offer
+func FindRematchOffer(client *redis.Client, gameID int) int {
+	userID, err := client.Get("Rematch-" + strconv.Itoa(gameID)).Int()
 	if err != nil {
 		return -1
 	}
 
-	i, _ := strconv.Atoi(userID)
+	return userID
+}
+
+// ClaimRematchOffer removes the rematch offer. Returns false if it was already taken
+func ClaimRematchOffer(client *redis.Client, gameID int) bool {
+	count, err := client.Del("Rematch-" + strconv.Itoa(gameID)).Result()
+	return err == nil && count == 1
+}
+
+// inviteKey is where the invite with the code is kept. Codes are not case sensitive
+func inviteKey(code string) string {
+	return "Invite-" + strings.ToUpper(strings.TrimSpace(code))
+}
+
+// StoreInvite gives the invite a code no other open invite has and keeps it until it expires
+func StoreInvite(client *redis.Client, invite Invite) (Invite, error) {
+
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := newInviteCode()
+		if err != nil {
+			return invite, err
+		}
+
+		invite.Code = code
+
+		value, err := json.Marshal(invite)
+		if err != nil {
+			return invite, err
+		}
+
+		stored, err := client.SetNX(inviteKey(code), value, InviteTime).Result()
+		if err != nil {
+			return invite, err
+		}
+
+		if stored {
+			return invite, nil
+		}
+	}
+
+	return invite, errors.New("Could not create an invite code")
+}
+
+// FindInvite finds the open invite with the code
+func FindInvite(client *redis.Client, code string) (Invite, bool) {
 
-	return i
+	var invite Invite
+
+	value, err := client.Get(inviteKey(code)).Bytes()
+	if err != nil {
+		return invite, false
+	}
+
+	return invite, json.Unmarshal(value, &invite) == nil
 }
 
-// AddToEndOfQueue adds the current user to the end of the queue
-func AddToEndOfQueue(client *redis.Client, userID int) {
-	client.RPush("WaitingQueue", userID)
+/*
+JoinInvite seats the player in the invite. The invite is taken down once every seat has been
+taken. The invite is watched so two players can not take the last seat at the same time
+*/
+func JoinInvite(client *redis.Client, code string, userID int) (Invite, error) {
+
+	key := inviteKey(code)
+	var joined Invite
+
+	err := client.Watch(func(tx *redis.Tx) error {
+		value, err := tx.Get(key).Bytes()
+		if err == redis.Nil {
+			return errInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var invite Invite
+		err = json.Unmarshal(value, &invite)
+		if err != nil {
+			return err
+		}
+
+		invite, err = invite.admit(userID)
+		if err != nil {
+			return err
+		}
+
+		ttl, err := tx.TTL(key).Result()
+		if err != nil {
+			return err
+		}
+		if ttl <= 0 {
+			return errInviteNotFound
+		}
+
+		value, err = json.Marshal(invite)
+		if err != nil {
+			return err
+		}
+
+		rules, _ := FindRuleSet(invite.RuleSet)
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			if invite.isFull(rules) {
+				pipe.Del(key)
+			} else {
+				pipe.Set(key, value, ttl)
+			}
+			return nil
+		})
+
+		if err == nil {
+			joined = invite
+		}
+
+		return err
+	}, key)
+
+	return joined, err
+}
+
+// RemoveInvite takes down the invite. Returns false if it had already gone
+func RemoveInvite(client *redis.Client, code string) bool {
+	count, err := client.Del(inviteKey(code)).Result()
+	return err == nil && count == 1
+}
+
+// AllowChatMessage counts a chat message against the player's rate limit. Returns false once
+// they have sent ChatRateLimit messages in the current window
+func AllowChatMessage(client *redis.Client, userID int) bool {
+
+	key := "ChatRate-" + strconv.Itoa(userID)
+
+	// The first message starts the window. The counter is created with its expiry so it can
+	// never be left without one
+	pipe := client.TxPipeline()
+	pipe.SetNX(key, 0, ChatRateWindow)
+	count := pipe.Incr(key)
+
+	_, err := pipe.Exec()
+	if err != nil {
+		return false
+	}
+
+	return count.Val() <= ChatRateLimit
+}
+
+// mutedKey is the set of players the user has muted
+func mutedKey(userID int) string {
+	return "Muted-" + strconv.Itoa(userID)
+}
+
+// MutePlayer stops chat from the player reaching the user
+func MutePlayer(client *redis.Client, userID int, playerID int) {
+	client.SAdd(mutedKey(userID), playerID)
+}
+
+// UnmutePlayer lets chat from the player reach the user again
+func UnmutePlayer(client *redis.Client, userID int, playerID int) {
+	client.SRem(mutedKey(userID), playerID)
+}
+
+// FindMutedPlayers finds the players the user has muted
+func FindMutedPlayers(client *redis.Client, userID int) []int {
+
+	members, err := client.SMembers(mutedKey(userID)).Result()
+	if err != nil {
+		return nil
+	}
+
+	var muted []int
+	for _, member := range members {
+		if playerID, err := strconv.Atoi(member); err == nil {
+			muted = append(muted, playerID)
+		}
+	}
+
+	return muted
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// maxChatLength is the longest chat message a player can send
+const maxChatLength = 500
+
+// ChatRateLimit is how many chat messages a player can send in each ChatRateWindow
+const ChatRateLimit = 5
+
+// ChatRateWindow is the window the chat rate limit is counted over
+const ChatRateWindow = time.Second * 10
+
+// ChatEventMessage is a chat message between the players of a game. The Client only sends
+// the GameID and the Text, the Server fills in the rest before passing it on
+type ChatEventMessage struct {
+	EventMessage
+	PlayerID int
+	Text     string
+	SentAt   time.Time
+}
+
+// ChatHistoryEventMessage is sent by the Client to ask for the chat of a game. The Server
+// answers with the Messages
+type ChatHistoryEventMessage struct {
+	EventMessage
+	Messages []ChatEventMessage
+}
+
+// MuteEventMessage is sent by the Client to stop or start seeing chat from a player
+type MuteEventMessage struct {
+	EventMessage
+	PlayerID int
+	Mute     bool
+}
+
+// SendChat stores a chat message in a running game of the player and passes it on to every
+// player in the game who has not muted the sender
+func SendChat(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message ChatEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	sendChat(db, cache, producer, gameID, message, userID)
+}
+
+// sendChat checks the message against the rate limit before storing it and passing it on
+func sendChat(db *sql.DB, cache *redis.Client, producer *kafka.Producer, gameID int, message ChatEventMessage, userID int) {
+
+	text, err := cleanChatText(message.Text)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, gameID)
+		return
+	}
+
+	if !AllowChatMessage(cache, userID) {
+		PublishErrorEvent(producer, "You are sending messages too quickly", userID, gameID)
+		return
+	}
+
+	chat := ChatEventMessage{
+		EventMessage: EventMessage{GameID: gameID},
+		PlayerID:     userID,
+		Text:         text,
+		SentAt:       time.Now(),
+	}
+
+	err = RecordChatMessage(db, chat)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, gameID)
+		return
+	}
+
+	for _, seat := range FindSeatsForGame(db, gameID) {
+		if seat.PlayerID != userID && containsInt(FindMutedPlayers(cache, seat.PlayerID), userID) {
+			continue
+		}
+
+		chatMessage := EventMessage{
+			Event:   ChatEvent,
+			To:      seat.PlayerID,
+			GameID:  gameID,
+			Payload: chat,
+		}
+
+		chatMessage.Send(producer)
+	}
+}
+
+// SendChatHistory sends the player the chat of a game they are playing in, leaving out players they have muted
+func SendChatHistory(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message ChatHistoryEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !isPlayerInGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	historyMessage := EventMessage{
+		Event:  ChatHistoryEvent,
+		To:     userID,
+		GameID: gameID,
+		Payload: ChatHistoryEventMessage{
+			EventMessage: EventMessage{GameID: gameID},
+			Messages:     withoutMuted(FindChatForGame(db, gameID), FindMutedPlayers(cache, userID)),
+		},
+	}
+
+	historyMessage.Send(producer)
+}
+
+// MuteChat stops or starts passing on chat from a player to the user
+func MuteChat(cache *redis.Client, producer *kafka.Producer, message MuteEventMessage, userID int) {
+
+	if message.PlayerID == userID {
+		PublishErrorEvent(producer, "You can not mute yourself", userID, 0)
+		return
+	}
+
+	if message.Mute {
+		MutePlayer(cache, userID, message.PlayerID)
+	} else {
+		UnmutePlayer(cache, userID, message.PlayerID)
+	}
+}
+
+// cleanChatText trims the text and checks it can be sent
+func cleanChatText(text string) (string, error) {
+
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return "", errors.New("Message is empty")
+	}
+
+	if len(text) > maxChatLength {
+		return "", errors.New("Message is too long")
+	}
+
+	return text, nil
+}
+
+// withoutMuted leaves out the messages sent by muted players
+func withoutMuted(messages []ChatEventMessage, muted []int) []ChatEventMessage {
+
+	var result []ChatEventMessage
+	for _, message := range messages {
+		if !containsInt(muted, message.PlayerID) {
+			result = append(result, message)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanChatText(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		text     string
+		expected string
+		valid    bool
+	}{
+		{"When the message is fine", "good game", "good game", true},
+		{"When the message has spaces around it", "  nice shot \n", "nice shot", true},
+		{"When the message is only spaces", "   ", "", false},
+		{"When the message is too long", strings.Repeat("a", maxChatLength+1), "", false},
+		{"When the message is as long as allowed", strings.Repeat("a", maxChatLength), strings.Repeat("a", maxChatLength), true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			text, err := cleanChatText(tc.text)
+
+			if (err == nil) != tc.valid {
+				t.Fatalf("Expecting the message to be valid %v but got error %v", tc.valid, err)
+			}
+
+			if text != tc.expected {
+				t.Fatalf("Expecting text %q but was %q", tc.expected, text)
+			}
+		})
+	}
+}
+
+func TestWithoutMuted(t *testing.T) {
+
+	messages := []ChatEventMessage{
+		{PlayerID: 1, Text: "hello"},
+		{PlayerID: 2, Text: "hi"},
+		{PlayerID: 3, Text: "spam"},
+		{PlayerID: 2, Text: "gl"},
+	}
+
+	tt := []struct {
+		name     string
+		muted    []int
+		expected []string
+	}{
+		{"When nobody is muted", nil, []string{"hello", "hi", "spam", "gl"}},
+		{"When one player is muted", []int{3}, []string{"hello", "hi", "gl"}},
+		{"When every player is muted", []int{1, 2, 3}, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var texts []string
+			for _, message := range withoutMuted(messages, tc.muted) {
+				texts = append(texts, message.Text)
+			}
+
+			if strings.Join(texts, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("Expecting messages %v but was %v", tc.expected, texts)
+			}
+		})
+	}
+}
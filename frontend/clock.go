@@ -0,0 +1,328 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// TimeoutRule defines what happens when a player runs out of time
+type TimeoutRule string
+
+const (
+	// TimeoutAutoPlace places the ships of a player who has not placed them in time
+	TimeoutAutoPlace TimeoutRule = "autoplace"
+
+	// TimeoutSkip passes the turn to the opponent when a player does not move in time
+	TimeoutSkip TimeoutRule = "skip"
+
+	// TimeoutForfeit ends the game with the opponent as the winner
+	TimeoutForfeit TimeoutRule = "forfeit"
+)
+
+// ClockSettings defines the deadlines for placing ships and for each turn.
+// A timeout of 0 turns off that clock
+type ClockSettings struct {
+	PlacementTimeout time.Duration
+	PlacementRule    TimeoutRule
+	TurnTimeout      time.Duration
+	TurnRule         TimeoutRule
+	TimeBank         time.Duration
+}
+
+// Clocks are the clock settings used by every game
+var Clocks ClockSettings
+
+// deadlineCheckInterval is how often the servers look for games where a player has run out of time
+const deadlineCheckInterval = time.Second
+
+/*
+LoadClockSettings reads the clock settings from the environment.
+PLACEMENT_TIMEOUT, TURN_TIMEOUT and TIME_BANK take durations such as 90s or 2m.
+PLACEMENT_TIMEOUT_RULE is autoplace or forfeit and TURN_TIMEOUT_RULE is skip or forfeit
+*/
+func LoadClockSettings() ClockSettings {
+	return ClockSettings{
+		PlacementTimeout: durationFromEnv("PLACEMENT_TIMEOUT"),
+		PlacementRule:    ruleFromEnv("PLACEMENT_TIMEOUT_RULE", TimeoutAutoPlace, TimeoutForfeit),
+		TurnTimeout:      durationFromEnv("TURN_TIMEOUT"),
+		TurnRule:         ruleFromEnv("TURN_TIMEOUT_RULE", TimeoutSkip, TimeoutForfeit),
+		TimeBank:         durationFromEnv("TIME_BANK"),
+	}
+}
+
+// durationFromEnv reads a duration from the environment. Returns 0 if it is not set
+func durationFromEnv(name string) time.Duration {
+
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic(name + " is not a valid duration " + err.Error())
+	}
+
+	return duration
+}
+
+// ruleFromEnv reads a timeout rule from the environment. The first allowed rule is the default
+func ruleFromEnv(name string, allowed ...TimeoutRule) TimeoutRule {
+
+	value := TimeoutRule(os.Getenv(name))
+	if value == "" {
+		return allowed[0]
+	}
+
+	for _, rule := range allowed {
+		if rule == value {
+			return rule
+		}
+	}
+
+	panic(name + " must be one of " + string(allowed[0]) + " or " + string(allowed[1]))
+}
+
+// placementDeadline works out when ship placement ends for a game created now
+func (c ClockSettings) placementDeadline(now time.Time) *time.Time {
+
+	if c.PlacementTimeout <= 0 {
+		return nil
+	}
+
+	deadline := now.Add(c.PlacementTimeout)
+	return &deadline
+}
+
+// timeBank is the starting time bank of each player in milliseconds
+func (c ClockSettings) timeBank() sql.NullInt64 {
+
+	if c.TimeBank <= 0 {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(c.TimeBank / time.Millisecond), Valid: true}
+}
+
+// turnDeadline works out when a turn starting now ends from the turn timeout and
+// what is left of the player's time bank
+func (c ClockSettings) turnDeadline(now time.Time, timeBank sql.NullInt64) *time.Time {
+
+	limit := c.TurnTimeout
+
+	if timeBank.Valid {
+		bank := time.Duration(timeBank.Int64) * time.Millisecond
+		if limit <= 0 || bank < limit {
+			limit = bank
+		}
+	} else if limit <= 0 {
+		return nil
+	}
+
+	deadline := now.Add(limit)
+	return &deadline
+}
+
+// GameClock is the state of the clocks of a game. MoveDays is set for correspondence games
+type GameClock struct {
+	Deadline      sql.NullTime
+	TurnStartedAt sql.NullTime
+	TimeBanks     map[int]sql.NullInt64
+	MoveDays      int
+}
+
+// turnDeadline works out when a turn of the player starting now ends. Correspondence games
+// give each turn the days for a move, other games use the server clocks
+func (c GameClock) turnDeadline(now time.Time, playerID int) *time.Time {
+
+	if c.MoveDays > 0 {
+		return Clocks.correspondence(c.MoveDays).turnDeadline(now, sql.NullInt64{})
+	}
+
+	return Clocks.turnDeadline(now, c.TimeBanks[playerID])
+}
+
+// deadline returns the current deadline or nil if there is none
+func (c GameClock) deadline() *time.Time {
+
+	if !c.Deadline.Valid {
+		return nil
+	}
+
+	return &c.Deadline.Time
+}
+
+// timeBankExhausted checks if the player has used up their time bank on the current turn
+func (c GameClock) timeBankExhausted(playerID int, now time.Time) bool {
+
+	bank := c.TimeBanks[playerID]
+	if !bank.Valid || !c.TurnStartedAt.Valid {
+		return false
+	}
+
+	used := now.Sub(c.TurnStartedAt.Time)
+	return time.Duration(bank.Int64)*time.Millisecond-used <= 0
+}
+
+/*
+WatchDeadlines looks for games where a player has run out of time and applies the timeout rule.
+Every server runs this. Each deadline is claimed in the database before it is applied so
+a timeout only fires once, and deadlines are stored with the game so they survive a restart
+*/
+func WatchDeadlines(db *sql.DB, cache *redis.Client, producer *kafka.Producer) {
+
+	for range time.Tick(deadlineCheckInterval) {
+		now := time.Now()
+
+		for _, expired := range FindExpiredGames(db, now) {
+			if expired.Turn == -1 {
+				handlePlacementTimeout(db, producer, expired, now)
+			} else {
+				handleTurnTimeout(db, producer, expired, now)
+			}
+		}
+	}
+}
+
+// handlePlacementTimeout places or forfeits the fleets of players who have not placed their ships in time.
+// When forfeiting, the game goes on if players on at least two teams have placed their ships
+func handlePlacementTimeout(db *sql.DB, producer *kafka.Producer, expired ExpiredGame, now time.Time) {
+
+	if !ClaimDeadlineForGame(db, expired.GameID, expired.Deadline) {
+		return
+	}
+
+	var missing []int
+
+	seats := FindSeatsForGame(db, expired.GameID)
+
+	for _, seat := range seats {
+		if len(FindShipsForPlayer(db, expired.GameID, seat.PlayerID)) == 0 {
+			missing = append(missing, seat.PlayerID)
+		}
+	}
+
+	if Clocks.PlacementRule == TimeoutForfeit {
+		forfeitPlacement(db, producer, expired.GameID, seats, missing)
+		return
+	}
+
+	r := rand.New(rand.NewSource(now.UnixNano()))
+	rules := FindRuleSetForGame(db, expired.GameID)
+
+	var unplaced []int
+
+	for _, playerID := range missing {
+		ships := RandomFleet(r, rules)
+
+		// The game starts once the last fleet is placed
+		if ships == nil || !placeFleet(db, producer, expired.GameID, playerID, ships, RandomMines(r, rules, ships)) {
+			log.Printf("Could not place ships for player %d in game %d", playerID, expired.GameID)
+			unplaced = append(unplaced, playerID)
+		}
+	}
+
+	// The deadline has already been claimed, so players whose fleet could not be placed
+	// forfeit rather than leave the game waiting with no deadline
+	if len(unplaced) > 0 {
+		forfeitPlacement(db, producer, expired.GameID, seats, unplaced)
+	}
+}
+
+// forfeitPlacement knocks the players who did not place a fleet out of the game. The game goes
+// on if more than one team is left, otherwise it is won by a player who placed or abandoned
+func forfeitPlacement(db *sql.DB, producer *kafka.Producer, gameID int, seats []Seat, missing []int) {
+
+	var placed []int
+	remaining := seats
+
+	for _, seat := range seats {
+		if containsInt(missing, seat.PlayerID) {
+			remaining = eliminateSeat(remaining, seat.PlayerID)
+		} else {
+			placed = append(placed, seat.PlayerID)
+		}
+	}
+
+	var err error
+
+	if teamsLeft(remaining) > 1 {
+		for _, playerID := range missing {
+			err = EliminatePlayer(db, gameID, playerID)
+
+			if err != nil {
+				log.Printf("Error eliminating player %d from game %d %s", playerID, gameID, err.Error())
+				return
+			}
+		}
+
+		startGame(db, producer, gameID)
+		return
+	}
+
+	if len(placed) > 0 {
+		err = CompleteGame(db, gameID, placed[0])
+	} else {
+		err = AbandonGame(db, gameID)
+	}
+
+	if err != nil {
+		log.Printf("Error ending game %d %s", gameID, err.Error())
+		return
+	}
+
+	PublishGameUpdates(db, producer, gameID)
+}
+
+/*
+handleTurnTimeout skips the turn of a player who has not moved in time or forfeits the game
+if the rule says so or the player has used up their time bank. While more than one team
+would be left, forfeiting eliminates the player and the game goes on
+*/
+func handleTurnTimeout(db *sql.DB, producer *kafka.Producer, expired ExpiredGame, now time.Time) {
+
+	seats := FindSeatsForGame(db, expired.GameID)
+	nextID := nextPlayer(seats, expired.Turn)
+	clock := FindClockForGame(db, expired.GameID)
+	deadline := clock.turnDeadline(now, nextID)
+
+	if Clocks.TurnRule == TimeoutForfeit || clock.timeBankExhausted(expired.Turn, now) {
+		if teamsLeft(eliminateSeat(seats, expired.Turn)) > 1 {
+			if !SkipTurnForGame(db, expired.GameID, expired.Deadline, expired.Turn, nextID, deadline, now) {
+				return
+			}
+
+			err := EliminatePlayer(db, expired.GameID, expired.Turn)
+			if err != nil {
+				log.Printf("Error eliminating player %d from game %d %s", expired.Turn, expired.GameID, err.Error())
+				return
+			}
+
+			PublishGameUpdates(db, producer, expired.GameID)
+
+			playBotTurn(db, producer, expired.GameID)
+			return
+		}
+
+		if !ForfeitGameOnDeadline(db, expired.GameID, expired.Deadline, nextOpponent(seats, expired.Turn)) {
+			return
+		}
+
+		PublishGameUpdates(db, producer, expired.GameID)
+		return
+	}
+
+	if !SkipTurnForGame(db, expired.GameID, expired.Deadline, expired.Turn, nextID, deadline, now) {
+		return
+	}
+
+	PublishGameUpdates(db, producer, expired.GameID)
+
+	playBotTurn(db, producer, expired.GameID)
+}
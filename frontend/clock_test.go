@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestTurnDeadline(t *testing.T) {
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	noBank := sql.NullInt64{}
+	bank := func(d time.Duration) sql.NullInt64 {
+		return sql.NullInt64{Int64: int64(d / time.Millisecond), Valid: true}
+	}
+
+	tt := []struct {
+		name             string
+		settings         ClockSettings
+		timeBank         sql.NullInt64
+		expectedDeadline time.Duration
+	}{
+		{"When there is no clock", ClockSettings{}, noBank, 0},
+		{"When there is only a turn timeout", ClockSettings{TurnTimeout: time.Minute}, noBank, time.Minute},
+		{"When the time bank is larger than the turn timeout", ClockSettings{TurnTimeout: time.Minute}, bank(5 * time.Minute), time.Minute},
+		{"When the time bank is smaller than the turn timeout", ClockSettings{TurnTimeout: time.Minute}, bank(20 * time.Second), 20 * time.Second},
+		{"When there is only a time bank", ClockSettings{}, bank(3 * time.Minute), 3 * time.Minute},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			deadline := tc.settings.turnDeadline(now, tc.timeBank)
+
+			if tc.expectedDeadline == 0 {
+				if deadline != nil {
+					t.Fatalf("Expecting no deadline but was %v", deadline)
+				}
+				return
+			}
+
+			if deadline == nil || !deadline.Equal(now.Add(tc.expectedDeadline)) {
+				t.Fatalf("Expecting deadline to be %v but was %v", now.Add(tc.expectedDeadline), deadline)
+			}
+		})
+	}
+}
+
+func TestGameClockTurnDeadline(t *testing.T) {
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	bank := sql.NullInt64{Int64: int64(20 * time.Second / time.Millisecond), Valid: true}
+
+	saved := Clocks
+	defer func() { Clocks = saved }()
+	Clocks = ClockSettings{TurnTimeout: time.Minute}
+
+	tt := []struct {
+		name             string
+		clock            GameClock
+		expectedDeadline time.Duration
+	}{
+		{"When it is a live game", GameClock{}, time.Minute},
+		{"When it is a live game with a time bank", GameClock{TimeBanks: map[int]sql.NullInt64{1: bank}}, 20 * time.Second},
+		{"When it is a correspondence game", GameClock{MoveDays: 3}, 72 * time.Hour},
+		{"When a correspondence game has a time bank", GameClock{MoveDays: 1, TimeBanks: map[int]sql.NullInt64{1: bank}}, 24 * time.Hour},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			deadline := tc.clock.turnDeadline(now, 1)
+
+			if deadline == nil || !deadline.Equal(now.Add(tc.expectedDeadline)) {
+				t.Fatalf("Expecting deadline to be %v but was %v", now.Add(tc.expectedDeadline), deadline)
+			}
+		})
+	}
+}
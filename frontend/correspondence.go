@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// maxMoveDays is the most days a correspondence game can give each player for a move
+const maxMoveDays = 14
+
+/*
+CorrespondenceGame is a running correspondence game of the player. YourMove is set when the
+game is waiting on the player, either to place their ships or to take their turn. Turn is 0
+while the fleets are being placed
+*/
+type CorrespondenceGame struct {
+	GameID   int
+	RuleSet  string
+	Players  []int
+	Turn     int
+	Deadline *time.Time
+	MoveDays int
+	YourMove bool
+}
+
+// OpenGameEventMessage is sent by the Client to pick a running game back up, for example
+// after coming back to a correspondence game
+type OpenGameEventMessage struct {
+	EventMessage
+}
+
+// correspondence gives the clock settings of a correspondence game. Each placement and turn
+// gets the days for a move and there are no time banks
+func (c ClockSettings) correspondence(moveDays int) ClockSettings {
+
+	moveTime := time.Duration(moveDays) * 24 * time.Hour
+
+	return ClockSettings{
+		PlacementTimeout: moveTime,
+		PlacementRule:    c.PlacementRule,
+		TurnTimeout:      moveTime,
+		TurnRule:         c.TurnRule,
+	}
+}
+
+// isValidMoveDays checks the days for a move can be used for a game. 0 is a live game
+func isValidMoveDays(moveDays int) bool {
+	return moveDays >= 0 && moveDays <= maxMoveDays
+}
+
+// SendCorrespondenceGames sends the player their running correspondence games, those waiting on them first
+func SendCorrespondenceGames(db *sql.DB, producer *kafka.Producer, userID int) {
+
+	gamesMessage := EventMessage{
+		Event:   CorrespondenceGamesEvent,
+		To:      userID,
+		Payload: FindCorrespondenceGames(db, userID),
+	}
+
+	gamesMessage.Send(producer)
+}
+
+/*
+OpenGame sends the player a running game they are in as if they had just joined it. The
+game is started again on the Client and, once their ships are placed, followed by the
+latest state of the boards
+*/
+func OpenGame(db *sql.DB, producer *kafka.Producer, message OpenGameEventMessage, userID int) {
+
+	if !IsSeatedInLiveGame(db, message.GameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, message.GameID)
+		return
+	}
+
+	startedMessage := EventMessage{
+		Event:   GameStartedEvent,
+		To:      userID,
+		GameID:  message.GameID,
+		Payload: gameStarted(db, message.GameID),
+	}
+
+	startedMessage.Send(producer)
+
+	if len(FindShipsForPlayer(db, message.GameID, userID)) == 0 {
+		return
+	}
+
+	updateMessage := EventMessage{
+		Event:   GameUpdateEvent,
+		To:      userID,
+		GameID:  message.GameID,
+		Payload: ConstructGameUpdateMessage(db, message.GameID, userID),
+	}
+
+	updateMessage.Send(producer)
+}
+
+// correspondenceGamesRoute serves the running correspondence games of the player
+func correspondenceGamesRoute(db *sql.DB, cache *redis.Client) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID := getUserID(cache, r)
+		if userID == -1 {
+			http.Error(w, "Not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FindCorrespondenceGames(db, userID))
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"log"
 	"os"
+	"time"
 
+	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 	"github.com/xo/dburl"
 )
@@ -58,119 +60,167 @@ func ValidateLogin(db *sql.DB, email string, password string) (int, error) {
 	return -1, errors.New("Invalid Password")
 }
 
-// CreateNewGame creates a new game in the database
-func CreateNewGame(db *sql.DB, firstUser int, secondUser int) int {
+// IsKnownPlayer checks the user exists and is a person rather than a bot
+func IsKnownPlayer(db *sql.DB, userID int) bool {
+
+	var count int
+
+	row := db.QueryRow("SELECT COUNT(1) FROM USERS WHERE Id = $1 AND bot IS NULL", userID)
+	err := row.Scan(&count)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return false
+	}
+
+	return count == 1
+}
+
+// CreateNewGame creates a new game in the database with the players seated in the order given
+func CreateNewGame(db *sql.DB, players []int, ruleSet string) (int, error) {
+	return createGame(db, players, ruleSet, 0, false)
+}
+
+// createGame creates a game where each player gets the days for a move. A live game has 0 days and uses the server clocks.
+// Bot games are tagged so they can be kept out of stats for people
+func createGame(db *sql.DB, players []int, ruleSet string, moveDays int, botGame bool) (int, error) {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return -1, err
+	}
+
+	gameID, err := createGameInTx(tx, players, ruleSet, moveDays, botGame)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return gameID, tx.Commit()
+}
+
+// createGameInTx creates the game and seats the players as part of a larger transaction
+func createGameInTx(tx *sql.Tx, players []int, ruleSet string, moveDays int, botGame bool) (int, error) {
 
 	var gameID int
 
-	row := db.QueryRow(`
-		INSERT INTO GAMES (player1, player2) 
-		VALUES ($1, $2) RETURNING Id`,
-		firstUser, secondUser)
+	rules, _ := FindRuleSet(ruleSet)
+
+	clocks := Clocks
+	if moveDays > 0 {
+		clocks = Clocks.correspondence(moveDays)
+	}
+
+	row := tx.QueryRow(`
+		INSERT INTO GAMES (ruleSet, deadline, moveDays, botGame) 
+		VALUES ($1, $2, $3, $4) RETURNING Id`,
+		ruleSet, clocks.placementDeadline(time.Now()), moveDays, botGame)
 	err := row.Scan(&gameID)
 
 	if err != nil {
-		log.Fatal(err.Error())
-		return -1
+		return -1, err
+	}
+
+	for seat, playerID := range players {
+		_, err := tx.Exec(`
+			INSERT INTO SEATS (gameID, playerID, seat, team, timeBank)
+			VALUES ($1, $2, $3, $4, $5)`,
+			gameID, playerID, seat, rules.teamFor(seat), clocks.timeBank())
+
+		if err != nil {
+			return -1, err
+		}
 	}
 
-	return gameID
+	return gameID, nil
 }
 
-// FindLatestGameForPlayer finds the latest Started game for player
-func FindLatestGameForPlayer(db *sql.DB, userID int) int {
-	var gameID int
+// IsSeatedInLiveGame checks the player has a seat in the game and the game is still being played
+func IsSeatedInLiveGame(db *sql.DB, gameID int, playerID int) bool {
+
+	var count int
 
 	row := db.QueryRow(`
-		SELECT Id FROM GAMES 
-		WHERE (player1 = $1 OR player2 = $1)
-		AND Status = 'Started'
-		ORDER BY Id desc
-		LIMIT 1
-	`, userID)
+		SELECT COUNT(1) FROM GAMES g
+		JOIN SEATS s ON s.gameID = g.Id
+		WHERE g.Id = $1 AND s.playerID = $2
+		AND g.status = 'Started'`, gameID, playerID)
 
-	err := row.Scan(&gameID)
+	err := row.Scan(&count)
 
 	if err != nil {
-		log.Fatal(err.Error())
-		return -1
+		log.Printf("Error reading from database %s", err.Error())
+		return false
 	}
 
-	return gameID
+	return count == 1
 }
 
-// CreateShipsInDatabase creates the ships for the players
-func CreateShipsInDatabase(db *sql.DB, userID int, gameID int, ships []Ship) error {
+// CreateShipsInDatabase creates the ships and mines for the players. Every location of a ship is stored
+// in SHIP_LOCATIONS so ships can have any number of locations in any shape. The mines are stored in the
+// same transaction as the ships mark the player as ready
+func CreateShipsInDatabase(db *sql.DB, userID int, gameID int, ships []Ship, mines []Coord) error {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, mine := range mines {
+		_, err := tx.Exec(`
+			INSERT INTO MINES (gameID, playerID, xlocation, ylocation)
+			VALUES ($1, $2, $3, $4)`,
+			gameID, userID, mine.X, mine.Y)
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
 	for _, ship := range ships {
 
-		xlocation1 := ship.Location[0].X
-		ylocation1 := ship.Location[0].Y
-		xlocation2, ylocation2, xlocation3, ylocation3, xlocation4, ylocation4, xlocation5, ylocation5 := -1, -1, -1, -1, -1, -1, -1, -1
-
-		if ship.Size > 1 {
-			xlocation2 = ship.Location[1].X
-			ylocation2 = ship.Location[1].Y
-		}
-
-		if ship.Size > 2 {
-			xlocation3 = ship.Location[2].X
-			ylocation3 = ship.Location[2].Y
-		}
-
-		if ship.Size > 3 {
-			xlocation4 = ship.Location[3].X
-			ylocation4 = ship.Location[3].Y
-		}
-
-		if ship.Size > 4 {
-			xlocation5 = ship.Location[4].X
-			ylocation5 = ship.Location[4].Y
-		}
-
-		_, err := db.Exec(`
-			INSERT INTO SHIPS (
-				gameID, playerID, size, sunk,
-				xlocation1, ylocation1,
-				xlocation2, ylocation2,
-				xlocation3, ylocation3,
-				xlocation4, ylocation4,
-				xlocation5, ylocation5
-			) VALUES (
-				$1, $2, $3, $4,
-				$5, $6,
-				$7, $8,
-				$9, $10,
-				$11, $12,
-				$13, $14
-			)
-		`, gameID, userID, ship.Size, ship.Sunk,
-			xlocation1, ylocation1,
-			xlocation2, ylocation2,
-			xlocation3, ylocation3,
-			xlocation4, ylocation4,
-			xlocation5, ylocation5)
+		if len(ship.Location) == 0 {
+			tx.Rollback()
+			return errors.New("Invalid ship locations")
+		}
+
+		var shipID int
+
+		row := tx.QueryRow(`
+			INSERT INTO SHIPS (gameID, playerID, size, sunk)
+			VALUES ($1, $2, $3, $4) RETURNING Id`,
+			gameID, userID, ship.Size, ship.Sunk)
 
+		err := row.Scan(&shipID)
 		if err != nil {
+			tx.Rollback()
 			return err
 		}
+
+		for i, location := range ship.Location {
+			_, err := tx.Exec(`
+				INSERT INTO SHIP_LOCATIONS (shipID, position, xlocation, ylocation, hit)
+				VALUES ($1, $2, $3, $4, $5)`,
+				shipID, i, location.X, location.Y, location.Hit)
+
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func HaveBothPlayersPlacedShips(db *sql.DB, gameID int) bool {
+// HaveAllPlayersPlacedShips checks every player still in the game has placed their ships
+func HaveAllPlayersPlacedShips(db *sql.DB, gameID int) bool {
 	row := db.QueryRow(`
 		SELECT COUNT(1)
-		FROM
-			(SELECT 
-				PLAYERID, COUNT(1) as count 
-			FROM 
-				SHIPS 
-			WHERE
-				(PLAYERID = (SELECT Player1 FROM GAMES WHERE ID = $1) 
-			OR PLAYERID = (SELECT Player2 FROM GAMES WHERE ID = $1)) 
-			AND GAMEID = $1 GROUP BY PLAYERID) a;
+		FROM SEATS s
+		WHERE s.gameID = $1 AND NOT s.eliminated
+		AND NOT EXISTS (SELECT 1 FROM SHIPS WHERE gameID = $1 AND playerID = s.playerID)
 	`, gameID)
 
 	var count int
@@ -181,22 +231,15 @@ func HaveBothPlayersPlacedShips(db *sql.DB, gameID int) bool {
 		return false
 	}
 
-	if count == 2 {
-		return true
-	} else {
-		return false
-	}
+	return count == 0
 }
 
-func getPlayerForGame(db *sql.DB, gameID int, playerID int) int {
+// getPlayerForGame finds the player in the seat (0 based) of the game
+func getPlayerForGame(db *sql.DB, gameID int, seat int) int {
 
 	var userID int
-	var row *sql.Row
-	if playerID == 0 {
-		row = db.QueryRow("SELECT PLAYER1 FROM GAMES WHERE ID = $1", gameID)
-	} else {
-		row = db.QueryRow("SELECT PLAYER2 FROM GAMES WHERE ID = $1", gameID)
-	}
+
+	row := db.QueryRow("SELECT playerID FROM SEATS WHERE gameID = $1 AND seat = $2", gameID, seat)
 
 	err := row.Scan(&userID)
 
@@ -217,7 +260,7 @@ func FindGameState(db *sql.DB, gameID int) (status string, winner int) {
 	err := row.Scan(&status, &win)
 
 	if err != nil {
-		log.Fatalf("Error reading from database %s", err.Error())
+		log.Printf("Error reading from database %s", err.Error())
 		return "Error", -1
 	}
 
@@ -234,14 +277,12 @@ func FindShipsForPlayer(db *sql.DB, gameID int, playerID int) (ships []Ship) {
 
 	rows, err := db.Query(`
 		SELECT 
-			size, sunk,
-			xlocation1, ylocation1,
-			xlocation2, ylocation2,
-			xlocation3, ylocation3,
-			xlocation4, ylocation4,
-			xlocation5, ylocation5
-		FROM SHIPS 
-		WHERE gameid = $1 AND playerid = $2`, gameID, playerID)
+			s.id, s.size, s.sunk,
+			l.xlocation, l.ylocation, l.hit
+		FROM SHIPS s
+		JOIN SHIP_LOCATIONS l ON l.shipID = s.id
+		WHERE s.gameid = $1 AND s.playerid = $2
+		ORDER BY s.id, l.position`, gameID, playerID)
 
 	if err != nil {
 		log.Fatalf("Error reading from database %s", err.Error())
@@ -252,59 +293,1255 @@ func FindShipsForPlayer(db *sql.DB, gameID int, playerID int) (ships []Ship) {
 
 	for rows.Next() {
 		var ship Ship
-		var xlocation1, ylocation1, xlocation2, ylocation2, xlocation3, ylocation3, xlocation4, ylocation4, xlocation5, ylocation5 int
+		var location Coord
 
-		err := rows.Scan(&ship.Size, &ship.Sunk, &xlocation1, &ylocation1, &xlocation2, &ylocation2, &xlocation3, &ylocation3, &xlocation4, &ylocation4, &xlocation5, &ylocation5)
+		err := rows.Scan(&ship.ID, &ship.Size, &ship.Sunk, &location.X, &location.Y, &location.Hit)
 
 		if err != nil {
 			log.Fatalf("Error reading from database %s", err.Error())
 			return nil
 		}
 
-		var coords []Coord
-		if xlocation1 != -1 && ylocation1 != -1 {
-			coord := Coord{
-				X: xlocation1,
-				Y: ylocation1,
-			}
-			coords = append(coords, coord)
+		// Locations of the same ship come one after the other
+		if len(ships) == 0 || ships[len(ships)-1].ID != ship.ID {
+			ships = append(ships, ship)
 		}
 
-		if xlocation2 != -1 && ylocation2 != -1 {
-			coord := Coord{
-				X: xlocation2,
-				Y: ylocation2,
-			}
-			coords = append(coords, coord)
+		last := &ships[len(ships)-1]
+		last.Location = append(last.Location, location)
+	}
+
+	return ships
+}
+
+// FindSeatsForGame finds the players of the game in the order they are seated
+func FindSeatsForGame(db *sql.DB, gameID int) (seats []Seat) {
+
+	rows, err := db.Query(`
+		SELECT playerID, seat, COALESCE(team, seat), eliminated
+		FROM SEATS
+		WHERE gameID = $1
+		ORDER BY seat`, gameID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var seat Seat
+
+		err := rows.Scan(&seat.PlayerID, &seat.Seat, &seat.Team, &seat.Eliminated)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
 		}
 
-		if xlocation3 != -1 && ylocation3 != -1 {
-			coord := Coord{
-				X: xlocation3,
-				Y: ylocation3,
-			}
-			coords = append(coords, coord)
+		seats = append(seats, seat)
+	}
+
+	return seats
+}
+
+// isPlayerInGame checks if the player has a seat in the game
+func isPlayerInGame(db *sql.DB, gameID int, playerID int) bool {
+
+	var count int
+
+	row := db.QueryRow(`
+		SELECT COUNT(1) FROM SEATS
+		WHERE gameID = $1 AND playerID = $2`, gameID, playerID)
+
+	err := row.Scan(&count)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return false
+	}
+
+	return count == 1
+}
+
+// FindOpponentForGame finds the next player on another team still in the game after the
+// player. In a two player game this is always the other player
+func FindOpponentForGame(db *sql.DB, gameID int, playerID int) int {
+	return nextOpponent(FindSeatsForGame(db, gameID), playerID)
+}
+
+// EliminatePlayer marks the player as out of the game. Eliminated players keep their seat
+// and are still sent every event of the game
+func EliminatePlayer(db *sql.DB, gameID int, playerID int) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		return eliminatePlayerInTx(tx, gameID, playerID)
+	})
+}
+
+// eliminatePlayerInTx marks the player as out of the game as part of a larger transaction
+func eliminatePlayerInTx(tx *sql.Tx, gameID int, playerID int) error {
+	_, err := tx.Exec(`
+		UPDATE SEATS SET eliminated = true
+		WHERE gameID = $1 AND playerID = $2`, gameID, playerID)
+	return err
+}
+
+// markShipLocationHitInTx records a hit against a location (0 based) of a ship as part of a larger transaction
+func markShipLocationHitInTx(tx *sql.Tx, shipID int, location int) error {
+
+	result, err := tx.Exec(`
+		UPDATE SHIP_LOCATIONS SET hit = true
+		WHERE shipID = $1 AND position = $2`, shipID, location)
+
+	if err == nil && !rowsUpdated(result, err) {
+		return errors.New("Invalid ship location")
+	}
+
+	return err
+}
+
+// markShipSunkInTx marks the ship as sunk as part of a larger transaction
+func markShipSunkInTx(tx *sql.Tx, shipID int) error {
+	_, err := tx.Exec("UPDATE SHIPS SET sunk = true WHERE ID = $1", shipID)
+	return err
+}
+
+// CompleteGame marks the game as completed with the winner
+func CompleteGame(db *sql.DB, gameID int, winner int) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		return completeGameInTx(tx, gameID, winner)
+	})
+}
+
+// completeGameInTx marks the game as completed with the winner as part of a larger transaction
+func completeGameInTx(tx *sql.Tx, gameID int, winner int) error {
+	_, err := tx.Exec(`
+		UPDATE GAMES SET status = 'Completed', winner = $2, deadline = NULL
+		WHERE ID = $1`, gameID, winner)
+	return err
+}
+
+// inTx runs the writes in one transaction. Nothing is written if any of them fails
+func inTx(db *sql.DB, writes func(tx *sql.Tx) error) error {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = writes(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindTurnForGame finds the player whose turn it is. If no turn has been set then -1 is returned
+func FindTurnForGame(db *sql.DB, gameID int) int {
+
+	var turn sql.NullInt64
+
+	row := db.QueryRow("SELECT turn FROM GAMES WHERE ID = $1", gameID)
+
+	err := row.Scan(&turn)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return -1
+	}
+
+	if !turn.Valid {
+		return -1
+	}
+
+	return int(turn.Int64)
+}
+
+// SetFirstTurnForGame sets the player who moves first and the deadline for their turn.
+// Returns false if the turn was already set
+func SetFirstTurnForGame(db *sql.DB, gameID int, playerID int, deadline *time.Time, now time.Time) bool {
+
+	result, err := db.Exec(`
+		UPDATE GAMES SET turn = $2, deadline = $3, turnStartedAt = $4
+		WHERE ID = $1 AND turn IS NULL AND status = 'Started'`, gameID, playerID, deadline, now)
+
+	return rowsUpdated(result, err)
+}
+
+// switchTurn is the update which hands the turn on and counts the move being played
+const switchTurn = `
+	UPDATE GAMES SET turn = $3, moves = moves + 1, deadline = $4, turnStartedAt = $5
+	WHERE ID = $1 AND turn = $2 AND status = 'Started'
+	RETURNING moves`
+
+// PassTurnForGame hands the turn to the next player without a move being played.
+// Returns false if it was not the player's turn or the game is no longer running
+func PassTurnForGame(db *sql.DB, gameID int, fromPlayerID int, toPlayerID int, deadline *time.Time, now time.Time) bool {
+
+	return changeTurn(db, gameID, fromPlayerID, now, `
+		UPDATE GAMES SET turn = $3, deadline = $4, turnStartedAt = $5
+		WHERE ID = $1 AND turn = $2 AND status = 'Started'
+		RETURNING moves`, gameID, fromPlayerID, toPlayerID, deadline, now) != -1
+}
+
+/*
+PlayMoveInDatabase hands the turn to the next player and runs the writes recording the move in the
+same transaction, so a move is either fully recorded or not played at all. Returns the number of
+the move, or -1 if it was not the player's turn or the game is no longer running
+*/
+func PlayMoveInDatabase(db *sql.DB, gameID int, fromPlayerID int, toPlayerID int, deadline *time.Time, now time.Time, writes func(tx *sql.Tx, moveNumber int) error) (int, error) {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return -1, err
+	}
+
+	moveNumber := changeTurnInTx(tx, gameID, fromPlayerID, now, switchTurn, gameID, fromPlayerID, toPlayerID, deadline, now)
+
+	if moveNumber == -1 {
+		tx.Rollback()
+		return -1, nil
+	}
+
+	err = writes(tx, moveNumber)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return -1, err
+	}
+
+	return moveNumber, nil
+}
+
+/*
+changeTurn runs the update which changes the turn of the game along with deducting the time taken
+on the turn from the time bank of the player. The update must only change the game while it is
+still the player's turn and return the number of moves. Returns -1 if the game was not updated
+*/
+func changeTurn(db *sql.DB, gameID int, fromPlayerID int, now time.Time, update string, args ...interface{}) int {
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return -1
+	}
+
+	moveNumber := changeTurnInTx(tx, gameID, fromPlayerID, now, update, args...)
+	if moveNumber == -1 {
+		tx.Rollback()
+		return -1
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return -1
+	}
+
+	return moveNumber
+}
+
+// changeTurnInTx changes the turn as part of a larger transaction. Returns -1 if the game was
+// not updated, leaving the caller to roll back
+func changeTurnInTx(tx *sql.Tx, gameID int, fromPlayerID int, now time.Time, update string, args ...interface{}) int {
+
+	var moveNumber int
+
+	_, err := tx.Exec(`
+		UPDATE SEATS SET timeBank = GREATEST(SEATS.timeBank - CAST(EXTRACT(EPOCH FROM ($3 - g.turnStartedAt)) * 1000 AS bigint), 0)
+		FROM GAMES g
+		WHERE SEATS.gameID = $1 AND SEATS.playerID = $2 AND g.ID = $1
+		AND SEATS.timeBank IS NOT NULL AND g.turnStartedAt IS NOT NULL`, gameID, fromPlayerID, now)
+
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return -1
+	}
+
+	err = tx.QueryRow(update, args...).Scan(&moveNumber)
+
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error writing to database %s", err.Error())
 		}
+		return -1
+	}
 
-		if xlocation4 != -1 && ylocation4 != -1 {
-			coord := Coord{
-				X: xlocation4,
-				Y: ylocation4,
-			}
-			coords = append(coords, coord)
+	return moveNumber
+}
+
+// rowsUpdated checks if a statement changed exactly one row
+func rowsUpdated(result sql.Result, err error) bool {
+
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return false
+	}
+
+	count, err := result.RowsAffected()
+
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return false
+	}
+
+	return count == 1
+}
+
+// RecordMove adds a move to the move log of the game
+func RecordMove(db *sql.DB, gameID int, move Move) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		return recordMoveInTx(tx, gameID, move)
+	})
+}
+
+// recordMoveInTx adds a move to the move log as part of a larger transaction
+func recordMoveInTx(tx *sql.Tx, gameID int, move Move) error {
+	_, err := tx.Exec(`
+		INSERT INTO MOVES (gameID, moveNumber, shot, kind, playerID, target, xlocation, ylocation, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		gameID, move.Number, move.Shot, move.Kind, move.PlayerID, move.Target, move.Location.X, move.Location.Y, move.Outcome)
+	return err
+}
+
+/*
+RelocateShipInDatabase hands the turn to the next player, records the move and moves every
+location of the ship in one transaction, keeping where each location was before and after the
+move so it can be replayed. Returns the move number, or -1 if it was not the player's turn or
+the game is no longer running
+*/
+func RelocateShipInDatabase(db *sql.DB, gameID int, move Move, toPlayerID int, deadline *time.Time, now time.Time, ship Ship, to []Coord) int {
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return -1
+	}
+
+	moveNumber := changeTurnInTx(tx, gameID, move.PlayerID, now, switchTurn, gameID, move.PlayerID, toPlayerID, deadline, now)
+
+	if moveNumber == -1 {
+		tx.Rollback()
+		return -1
+	}
+
+	move.Number = moveNumber
+	err = recordMoveInTx(tx, gameID, move)
+
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error writing to database %s", err.Error())
+		return -1
+	}
+
+	for i, location := range ship.Location {
+		_, err := tx.Exec(`
+			INSERT INTO RELOCATIONS (gameID, moveNumber, shipID, position, fromX, fromY, toX, toY)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			gameID, moveNumber, ship.ID, i, location.X, location.Y, to[i].X, to[i].Y)
+
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error writing to database %s", err.Error())
+			return -1
 		}
 
-		if xlocation5 != -1 && ylocation5 != -1 {
-			coord := Coord{
-				X: xlocation5,
-				Y: ylocation5,
-			}
-			coords = append(coords, coord)
+		_, err = tx.Exec(`
+			UPDATE SHIP_LOCATIONS SET xlocation = $3, ylocation = $4
+			WHERE shipID = $1 AND position = $2`,
+			ship.ID, i, to[i].X, to[i].Y)
+
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error writing to database %s", err.Error())
+			return -1
 		}
+	}
 
-		ship.Location = coords
-		ships = append(ships, ship)
+	err = tx.Commit()
+
+	if err != nil {
+		log.Printf("Error writing to database %s", err.Error())
+		return -1
 	}
 
-	return ships
+	return moveNumber
+}
+
+// FindMovesForGame finds every move in the game in the order they were played
+func FindMovesForGame(db *sql.DB, gameID int) (moves []Move) {
+
+	rows, err := db.Query(`
+		SELECT moveNumber, shot, kind, playerID, COALESCE(target, 0), xlocation, ylocation, outcome, createdAt
+		FROM MOVES
+		WHERE gameid = $1
+		ORDER BY moveNumber, shot`, gameID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var move Move
+
+		err := rows.Scan(&move.Number, &move.Shot, &move.Kind, &move.PlayerID, &move.Target, &move.Location.X, &move.Location.Y, &move.Outcome, &move.CreatedAt)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
+		}
+
+		moves = append(moves, move)
+	}
+
+	return addRelocations(db, gameID, moves)
+}
+
+// addRelocations fills in where the ship went for every relocation in the moves
+func addRelocations(db *sql.DB, gameID int, moves []Move) []Move {
+
+	rows, err := db.Query(`
+		SELECT moveNumber, shipID, fromX, fromY, toX, toY
+		FROM RELOCATIONS
+		WHERE gameid = $1
+		ORDER BY moveNumber, position`, gameID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return moves
+	}
+
+	defer rows.Close()
+
+	byNumber := make(map[int]*Move)
+	for i := range moves {
+		if moves[i].Kind == MoveRelocation {
+			byNumber[moves[i].Number] = &moves[i]
+		}
+	}
+
+	for rows.Next() {
+		var moveNumber, shipID int
+		var from, to Coord
+
+		err := rows.Scan(&moveNumber, &shipID, &from.X, &from.Y, &to.X, &to.Y)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return moves
+		}
+
+		if move, ok := byNumber[moveNumber]; ok {
+			move.ShipID = shipID
+			move.From = append(move.From, from)
+			move.To = append(move.To, to)
+		}
+	}
+
+	return moves
+}
+
+// FindShotsAtPlayer finds every shot fired at the fleet of the player in the game in the order they were fired
+func FindShotsAtPlayer(db *sql.DB, gameID int, playerID int) (shots []Coord) {
+
+	rows, err := db.Query(`
+		SELECT xlocation, ylocation
+		FROM MOVES
+		WHERE gameid = $1 AND target = $2 AND kind = 'shot'
+		ORDER BY moveNumber, shot`, gameID, playerID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var shot Coord
+
+		err := rows.Scan(&shot.X, &shot.Y)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
+		}
+
+		shots = append(shots, shot)
+	}
+
+	return shots
+}
+
+// CountShipsAfloat counts the ships of the player in the game which have not been sunk
+func CountShipsAfloat(db *sql.DB, gameID int, playerID int) int {
+
+	var count int
+
+	row := db.QueryRow(`
+		SELECT COUNT(*) FROM SHIPS
+		WHERE gameid = $1 AND playerid = $2 AND NOT sunk`, gameID, playerID)
+	err := row.Scan(&count)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return 0
+	}
+
+	return count
+}
+
+// FindMinesForPlayer finds the mines the player placed in the game
+func FindMinesForPlayer(db *sql.DB, gameID int, playerID int) []Coord {
+	return findLocations(db, `
+		SELECT xlocation, ylocation
+		FROM MINES
+		WHERE gameid = $1 AND playerid = $2
+		ORDER BY id`, gameID, playerID)
+}
+
+// recordRevealInTx records a ship location of the player given away by a mine in the move as part of a larger transaction
+func recordRevealInTx(tx *sql.Tx, gameID int, playerID int, moveNumber int, location Coord) error {
+	_, err := tx.Exec(`
+		INSERT INTO REVEALS (gameID, playerID, moveNumber, xlocation, ylocation)
+		VALUES ($1, $2, $3, $4, $5)`,
+		gameID, playerID, moveNumber, location.X, location.Y)
+	return err
+}
+
+// FindRevealsForPlayer finds the ship locations of the player given away by mines
+func FindRevealsForPlayer(db *sql.DB, gameID int, playerID int) []Coord {
+	return findLocations(db, `
+		SELECT xlocation, ylocation
+		FROM REVEALS
+		WHERE gameid = $1 AND playerid = $2
+		ORDER BY moveNumber`, gameID, playerID)
+}
+
+// findLocations reads the coordinates returned by a query
+func findLocations(db *sql.DB, query string, args ...interface{}) (locations []Coord) {
+
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var location Coord
+
+		err := rows.Scan(&location.X, &location.Y)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations
+}
+
+// recordAbilityUseInTx records the player using an ability in the move as part of a larger transaction
+func recordAbilityUseInTx(tx *sql.Tx, gameID int, playerID int, ability Ability, moveNumber int, location Coord) error {
+	_, err := tx.Exec(`
+		INSERT INTO ABILITIES (gameID, playerID, ability, moveNumber, xlocation, ylocation)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		gameID, playerID, ability, moveNumber, location.X, location.Y)
+	return err
+}
+
+// CountAbilityUses counts how many times the player has used each ability in the game
+func CountAbilityUses(db *sql.DB, gameID int, playerID int) map[Ability]int {
+
+	uses := make(map[Ability]int)
+
+	rows, err := db.Query(`
+		SELECT ability, COUNT(*)
+		FROM ABILITIES
+		WHERE gameid = $1 AND playerid = $2
+		GROUP BY ability`, gameID, playerID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return uses
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ability Ability
+		var count int
+
+		err := rows.Scan(&ability, &count)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return uses
+		}
+
+		uses[ability] = count
+	}
+
+	return uses
+}
+
+// ResignGame completes a running game with the winner. Returns false if the game is not running
+func ResignGame(db *sql.DB, gameID int, winner int) bool {
+
+	result, err := db.Exec(`
+		UPDATE GAMES SET status = 'Completed', winner = $2, deadline = NULL
+		WHERE ID = $1 AND status = 'Started'`, gameID, winner)
+
+	return rowsUpdated(result, err)
+}
+
+// AbandonGame ends a game without a winner
+func AbandonGame(db *sql.DB, gameID int) error {
+	_, err := db.Exec(`
+		UPDATE GAMES SET status = 'Abandoned', deadline = NULL
+		WHERE ID = $1`, gameID)
+	return err
+}
+
+// FindClockForGame finds the deadline and time banks of a game
+func FindClockForGame(db *sql.DB, gameID int) GameClock {
+
+	var clock GameClock
+
+	row := db.QueryRow(`
+		SELECT deadline, turnStartedAt, moveDays
+		FROM GAMES WHERE ID = $1`, gameID)
+
+	err := row.Scan(&clock.Deadline, &clock.TurnStartedAt, &clock.MoveDays)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+	}
+
+	clock.TimeBanks = make(map[int]sql.NullInt64)
+
+	rows, err := db.Query("SELECT playerID, timeBank FROM SEATS WHERE gameID = $1", gameID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return clock
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var playerID int
+		var timeBank sql.NullInt64
+
+		err := rows.Scan(&playerID, &timeBank)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return clock
+		}
+
+		clock.TimeBanks[playerID] = timeBank
+	}
+
+	return clock
+}
+
+// ExpiredGame is a running game where the deadline has passed.
+// Turn is -1 if the players are still placing ships
+type ExpiredGame struct {
+	GameID   int
+	Deadline time.Time
+	Turn     int
+}
+
+// FindExpiredGames finds running games where the deadline has passed
+func FindExpiredGames(db *sql.DB, now time.Time) (games []ExpiredGame) {
+
+	rows, err := db.Query(`
+		SELECT id, deadline, COALESCE(turn, -1)
+		FROM GAMES
+		WHERE status = 'Started' AND deadline < $1`, now)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var game ExpiredGame
+
+		err := rows.Scan(&game.GameID, &game.Deadline, &game.Turn)
+
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
+		}
+
+		games = append(games, game)
+	}
+
+	return games
+}
+
+// ClaimDeadlineForGame clears a deadline so that only one server handles it.
+// Returns false if another server has already handled it or a player has moved since
+func ClaimDeadlineForGame(db *sql.DB, gameID int, deadline time.Time) bool {
+
+	result, err := db.Exec(`
+		UPDATE GAMES SET deadline = NULL
+		WHERE ID = $1 AND deadline = $2 AND status = 'Started'`, gameID, deadline)
+
+	return rowsUpdated(result, err)
+}
+
+// ForfeitGameOnDeadline completes the game with the winner if the deadline has not already been handled
+func ForfeitGameOnDeadline(db *sql.DB, gameID int, deadline time.Time, winner int) bool {
+
+	result, err := db.Exec(`
+		UPDATE GAMES SET status = 'Completed', winner = $3, deadline = NULL
+		WHERE ID = $1 AND deadline = $2 AND status = 'Started'`, gameID, deadline, winner)
+
+	return rowsUpdated(result, err)
+}
+
+// SkipTurnForGame passes the turn to the next player when the deadline passes without a move.
+// Returns false if the deadline has already been handled or a move was made in time
+func SkipTurnForGame(db *sql.DB, gameID int, expiredDeadline time.Time, fromPlayerID int, toPlayerID int, deadline *time.Time, now time.Time) bool {
+
+	return changeTurn(db, gameID, fromPlayerID, now, `
+		UPDATE GAMES SET turn = $3, deadline = $4, turnStartedAt = $5
+		WHERE ID = $1 AND turn = $2 AND status = 'Started' AND deadline = $6
+		RETURNING moves`, gameID, fromPlayerID, toPlayerID, deadline, now, expiredDeadline) != -1
+}
+
+// FindOrCreateBotUser finds the user that plays as the bot of the difficulty, creating it if needed
+func FindOrCreateBotUser(db *sql.DB, difficulty BotDifficulty) (int, error) {
+
+	var userID int
+
+	row := db.QueryRow("SELECT id FROM USERS WHERE bot = $1", difficulty)
+	err := row.Scan(&userID)
+
+	if err == nil {
+		return userID, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return -1, err
+	}
+
+	// Bots never log in so they get a password nobody knows
+	row = db.QueryRow(`
+		INSERT INTO USERS (email, password, bot)
+		VALUES ($1, $2, $3) RETURNING Id`,
+		"bot-"+string(difficulty)+"@battleship", uuid.New().String(), difficulty)
+
+	err = row.Scan(&userID)
+
+	if err != nil {
+		return -1, err
+	}
+
+	return userID, nil
+}
+
+// FindBotDifficulty finds the difficulty of a bot user. Returns an empty difficulty for people
+func FindBotDifficulty(db *sql.DB, userID int) BotDifficulty {
+
+	var difficulty sql.NullString
+
+	row := db.QueryRow("SELECT bot FROM USERS WHERE ID = $1", userID)
+	err := row.Scan(&difficulty)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return ""
+	}
+
+	return BotDifficulty(difficulty.String)
+}
+
+// CreateTournamentInDatabase creates a tournament open for players to sign up to
+func CreateTournamentInDatabase(db *sql.DB, tournament Tournament) (int, error) {
+
+	var tournamentID int
+
+	row := db.QueryRow(`
+		INSERT INTO TOURNAMENTS (name, format, ruleSet, organizer, rounds, startsAt)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING Id`,
+		tournament.Name, tournament.Format, tournament.RuleSet, tournament.Organizer, tournament.Rounds, tournament.StartsAt)
+
+	err := row.Scan(&tournamentID)
+	if err != nil {
+		return -1, err
+	}
+
+	return tournamentID, nil
+}
+
+// SignUpForTournament adds the player to a tournament that has not started yet.
+// Returns false if sign up has closed or the player has already signed up
+func SignUpForTournament(db *sql.DB, tournamentID int, playerID int) bool {
+
+	result, err := db.Exec(`
+		INSERT INTO TOURNAMENT_PLAYERS (tournamentID, playerID)
+		SELECT Id, $2 FROM TOURNAMENTS
+		WHERE Id = $1 AND status = 'SigningUp'
+		ON CONFLICT (tournamentID, playerID) DO NOTHING`, tournamentID, playerID)
+
+	return rowsUpdated(result, err)
+}
+
+// FindTournament finds a tournament with its players in seed order and every match played so far
+func FindTournament(db *sql.DB, tournamentID int) (Tournament, error) {
+
+	var tournament Tournament
+	var startsAt, roundDeadline sql.NullTime
+
+	row := db.QueryRow(`
+		SELECT Id, name, format, ruleSet, organizer, status, rounds, round, startsAt, roundDeadline, COALESCE(winner, 0)
+		FROM TOURNAMENTS
+		WHERE Id = $1`, tournamentID)
+
+	err := row.Scan(&tournament.ID, &tournament.Name, &tournament.Format, &tournament.RuleSet, &tournament.Organizer,
+		&tournament.Status, &tournament.Rounds, &tournament.Round, &startsAt, &roundDeadline, &tournament.Winner)
+
+	if err != nil {
+		return tournament, err
+	}
+
+	if startsAt.Valid {
+		tournament.StartsAt = &startsAt.Time
+	}
+
+	if roundDeadline.Valid {
+		tournament.RoundDeadline = &roundDeadline.Time
+	}
+
+	rows, err := db.Query(`
+		SELECT playerID, COALESCE(seed, 0)
+		FROM TOURNAMENT_PLAYERS
+		WHERE tournamentID = $1
+		ORDER BY seed, Id`, tournamentID)
+
+	if err != nil {
+		return tournament, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var player TournamentPlayer
+
+		err := rows.Scan(&player.PlayerID, &player.Seed)
+		if err != nil {
+			return tournament, err
+		}
+
+		tournament.Players = append(tournament.Players, player)
+	}
+
+	matches, err := db.Query(`
+		SELECT Id, round, slot, player1, COALESCE(player2, 0), COALESCE(gameID, 0), COALESCE(winner, 0), forfeit
+		FROM TOURNAMENT_MATCHES
+		WHERE tournamentID = $1
+		ORDER BY round, slot`, tournamentID)
+
+	if err != nil {
+		return tournament, err
+	}
+
+	defer matches.Close()
+
+	for matches.Next() {
+		var match TournamentMatch
+
+		err := matches.Scan(&match.ID, &match.Round, &match.Slot, &match.Player1, &match.Player2,
+			&match.GameID, &match.Winner, &match.Forfeit)
+		if err != nil {
+			return tournament, err
+		}
+
+		tournament.Matches = append(tournament.Matches, match)
+	}
+
+	return tournament, nil
+}
+
+// FindOpenTournaments finds the tournaments that are signing players up or being played
+func FindOpenTournaments(db *sql.DB) []int {
+	return findTournaments(db, `
+		SELECT Id FROM TOURNAMENTS
+		WHERE status IN ('SigningUp', 'Started')
+		ORDER BY Id`)
+}
+
+// FindTournamentsToAdvance finds the tournaments being played and those due to start
+func FindTournamentsToAdvance(db *sql.DB, now time.Time) []int {
+	return findTournaments(db, `
+		SELECT Id FROM TOURNAMENTS
+		WHERE status = 'Started' OR (status = 'SigningUp' AND startsAt <= $1)
+		ORDER BY Id`, now)
+}
+
+// findTournaments reads the ids of the tournaments the query selects
+func findTournaments(db *sql.DB, query string, args ...interface{}) (tournaments []int) {
+
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tournamentID int
+
+		err := rows.Scan(&tournamentID)
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
+		}
+
+		tournaments = append(tournaments, tournamentID)
+	}
+
+	return tournaments
+}
+
+/*
+StartTournamentInDatabase closes sign up and seeds the players. Players who have won the most
+games are seeded highest, with ties going to whoever signed up first. Returns false if the
+tournament has already started
+*/
+func StartTournamentInDatabase(db *sql.DB, tournamentID int) bool {
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting tournament %d %s", tournamentID, err.Error())
+		return false
+	}
+
+	result, err := tx.Exec(`
+		UPDATE TOURNAMENTS SET status = 'Started'
+		WHERE Id = $1 AND status = 'SigningUp'`, tournamentID)
+
+	if !rowsUpdated(result, err) {
+		tx.Rollback()
+		return false
+	}
+
+	_, err = tx.Exec(`
+		UPDATE TOURNAMENT_PLAYERS SET seed = ranked.seed
+		FROM (
+			SELECT p.Id, ROW_NUMBER() OVER (ORDER BY COUNT(g.Id) DESC, p.Id) AS seed
+			FROM TOURNAMENT_PLAYERS p
+			LEFT JOIN GAMES g ON g.winner = p.playerID AND NOT g.botGame
+			WHERE p.tournamentID = $1
+			GROUP BY p.Id
+		) ranked
+		WHERE TOURNAMENT_PLAYERS.Id = ranked.Id`, tournamentID)
+
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error seeding tournament %d %s", tournamentID, err.Error())
+		return false
+	}
+
+	return tx.Commit() == nil
+}
+
+// CancelTournament ends a tournament that never started
+func CancelTournament(db *sql.DB, tournamentID int) bool {
+
+	result, err := db.Exec(`
+		UPDATE TOURNAMENTS SET status = 'Cancelled'
+		WHERE Id = $1 AND status = 'SigningUp'`, tournamentID)
+
+	return rowsUpdated(result, err)
+}
+
+/*
+StartTournamentRound moves the tournament on to the next round and records its matches, with a
+game for every match that is not a bye, all in one transaction so that only one server starts
+each round and a round is never left without its games. Returns the games created, or false if
+another server has already moved it on or the round could not be created
+*/
+func StartTournamentRound(db *sql.DB, tournament Tournament, matches []TournamentMatch, deadline *time.Time) ([]int, bool) {
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting round in tournament %d %s", tournament.ID, err.Error())
+		return nil, false
+	}
+
+	result, err := tx.Exec(`
+		UPDATE TOURNAMENTS SET round = $2 + 1, roundDeadline = $3
+		WHERE Id = $1 AND round = $2 AND status = 'Started'`, tournament.ID, tournament.Round, deadline)
+
+	if !rowsUpdated(result, err) {
+		tx.Rollback()
+		return nil, false
+	}
+
+	var games []int
+
+	for _, match := range matches {
+		if match.Player2 != 0 {
+			match.GameID, err = createGameInTx(tx, []int{match.Player1, match.Player2}, tournament.RuleSet, 0, false)
+			if err != nil {
+				tx.Rollback()
+				log.Printf("Error creating game in tournament %d %s", tournament.ID, err.Error())
+				return nil, false
+			}
+
+			games = append(games, match.GameID)
+		}
+
+		// Byes are stored without a player2 or game
+		_, err = tx.Exec(`
+			INSERT INTO TOURNAMENT_MATCHES (tournamentID, round, slot, player1, player2, gameID, winner)
+			VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), NULLIF($7, 0))`,
+			tournament.ID, match.Round, match.Slot, match.Player1, match.Player2, match.GameID, match.Winner)
+
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error creating match in tournament %d %s", tournament.ID, err.Error())
+			return nil, false
+		}
+	}
+
+	return games, tx.Commit() == nil
+}
+
+// RecordMatchWinner records who won a match. Returns false if the match already has a winner
+func RecordMatchWinner(db *sql.DB, matchID int, winner int, forfeit bool) bool {
+
+	result, err := db.Exec(`
+		UPDATE TOURNAMENT_MATCHES SET winner = $2, forfeit = $3
+		WHERE Id = $1 AND winner IS NULL`, matchID, winner, forfeit)
+
+	return rowsUpdated(result, err)
+}
+
+// CompleteTournament marks the tournament as won. Returns false if it has already been completed
+func CompleteTournament(db *sql.DB, tournamentID int, winner int) bool {
+
+	result, err := db.Exec(`
+		UPDATE TOURNAMENTS SET status = 'Completed', winner = $2, roundDeadline = NULL
+		WHERE Id = $1 AND status = 'Started'`, tournamentID, winner)
+
+	return rowsUpdated(result, err)
+}
+
+// IsLiveGame checks the game exists and is still being played
+func IsLiveGame(db *sql.DB, gameID int) bool {
+
+	var count int
+
+	row := db.QueryRow("SELECT COUNT(1) FROM GAMES WHERE Id = $1 AND status = 'Started'", gameID)
+	err := row.Scan(&count)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return false
+	}
+
+	return count == 1
+}
+
+// FindGameForRecord finds the state of the game and the day it was created. Returns false if there is no such game
+func FindGameForRecord(db *sql.DB, gameID int) (status string, winner int, date time.Time, ok bool) {
+
+	var win sql.NullInt64
+
+	row := db.QueryRow("SELECT status, winner, createdAt FROM GAMES WHERE Id = $1", gameID)
+	err := row.Scan(&status, &win, &date)
+
+	if err == sql.ErrNoRows {
+		return "", 0, time.Time{}, false
+	}
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return "", 0, time.Time{}, false
+	}
+
+	if win.Valid {
+		winner = int(win.Int64)
+	}
+
+	y, m, d := date.UTC().Date()
+	return status, winner, time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true
+}
+
+// CountSpectator adds to or takes away from the number of spectators watching the game
+func CountSpectator(db *sql.DB, gameID int, change int) error {
+	_, err := db.Exec(`
+		UPDATE GAMES SET spectators = GREATEST(spectators + $2, 0)
+		WHERE Id = $1`, gameID, change)
+	return err
+}
+
+// CountSpectators finds how many spectators are watching the game across every server
+func CountSpectators(db *sql.DB, gameID int) int {
+
+	var count int
+
+	row := db.QueryRow("SELECT spectators FROM GAMES WHERE Id = $1", gameID)
+	err := row.Scan(&count)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return 0
+	}
+
+	return count
+}
+
+// FindLiveGames finds running games where every fleet has been placed. Games with the
+// most spectators come first, then those furthest along
+func FindLiveGames(db *sql.DB, limit int) (games []LiveGame) {
+
+	rows, err := db.Query(`
+		SELECT Id, ruleSet, moves, spectators
+		FROM GAMES
+		WHERE status = 'Started' AND turn IS NOT NULL
+		ORDER BY spectators DESC, moves DESC, Id DESC
+		LIMIT $1`, limit)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	for rows.Next() {
+		var game LiveGame
+
+		err := rows.Scan(&game.GameID, &game.RuleSet, &game.Moves, &game.Spectators)
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			rows.Close()
+			return nil
+		}
+
+		games = append(games, game)
+	}
+
+	rows.Close()
+
+	for i := range games {
+		for _, seat := range FindSeatsForGame(db, games[i].GameID) {
+			games[i].Players = append(games[i].Players, seat.PlayerID)
+		}
+	}
+
+	return games
+}
+
+// FindCorrespondenceGames finds the running correspondence games of the player. Games waiting
+// on the player come first, then those with the nearest deadline
+func FindCorrespondenceGames(db *sql.DB, userID int) (games []CorrespondenceGame) {
+
+	rows, err := db.Query(`
+		SELECT g.Id, g.ruleSet, COALESCE(g.turn, 0), g.deadline, g.moveDays,
+			NOT s.eliminated AND (g.turn = s.playerID OR (g.turn IS NULL AND NOT EXISTS (
+				SELECT 1 FROM SHIPS sh WHERE sh.gameID = g.Id AND sh.playerID = s.playerID)))
+		FROM GAMES g
+		JOIN SEATS s ON s.gameID = g.Id
+		WHERE s.playerID = $1 AND g.status = 'Started' AND g.moveDays > 0
+		ORDER BY 6 DESC, g.deadline ASC NULLS LAST, g.Id`, userID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	for rows.Next() {
+		var game CorrespondenceGame
+		var deadline sql.NullTime
+
+		err := rows.Scan(&game.GameID, &game.RuleSet, &game.Turn, &deadline, &game.MoveDays, &game.YourMove)
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			rows.Close()
+			return nil
+		}
+
+		if deadline.Valid {
+			game.Deadline = &deadline.Time
+		}
+
+		games = append(games, game)
+	}
+
+	rows.Close()
+
+	for i := range games {
+		for _, seat := range FindSeatsForGame(db, games[i].GameID) {
+			games[i].Players = append(games[i].Players, seat.PlayerID)
+		}
+	}
+
+	return games
+}
+
+// RecordChatMessage stores a chat message with the game it was sent in
+func RecordChatMessage(db *sql.DB, message ChatEventMessage) error {
+	_, err := db.Exec(`
+		INSERT INTO CHAT (gameID, playerID, message, sentAt)
+		VALUES ($1, $2, $3, $4)`,
+		message.GameID, message.PlayerID, message.Text, message.SentAt)
+	return err
+}
+
+// FindChatForGame finds every chat message sent in the game in the order they were sent
+func FindChatForGame(db *sql.DB, gameID int) (messages []ChatEventMessage) {
+
+	rows, err := db.Query(`
+		SELECT playerID, message, sentAt
+		FROM CHAT
+		WHERE gameID = $1
+		ORDER BY sentAt, Id`, gameID)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+		return nil
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		message := ChatEventMessage{EventMessage: EventMessage{GameID: gameID}}
+
+		err := rows.Scan(&message.PlayerID, &message.Text, &message.SentAt)
+		if err != nil {
+			log.Printf("Error reading from database %s", err.Error())
+			return nil
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages
 }
@@ -15,8 +15,15 @@ func main() {
 	db := ConnectDB()
 	cache := ConnectCache()
 	producer := ConnectProducer()
+	Clocks = LoadClockSettings()
+	Matchmaking = LoadMatchmakingSettings()
+	Tournaments = LoadTournamentSettings()
+	Spectating = LoadSpectatorSettings()
 
 	go WatchGameUpdates()
+	go WatchDeadlines(db, cache, producer)
+	go WatchWaitingQueue(db, cache, producer)
+	go WatchTournaments(db, producer)
 
 	log.Printf("Connected to database")
 
@@ -30,6 +37,10 @@ func main() {
 	http.HandleFunc("/", rootRoute(cache))
 	http.HandleFunc("/login", loginRoute(db, cache))
 	http.HandleFunc("/events", SocketHandler(db, cache, producer))
+	http.HandleFunc("/tournaments", tournamentsRoute(db, cache))
+	http.HandleFunc("/games/live", liveGamesRoute(db, cache))
+	http.HandleFunc("/games/record", gameRecordRoute(db, cache))
+	http.HandleFunc("/games/correspondence", correspondenceGamesRoute(db, cache))
 
 	log.Printf("Server started on port %s", port)
 	err := http.ListenAndServe(":"+port, nil)
@@ -66,11 +77,17 @@ func loginRoute(db *sql.DB, cache *redis.Client) func(http.ResponseWriter, *http
 			if err != nil {
 				w.Write([]byte("Error " + err.Error()))
 			} else {
-				sessionID := GenerateSession(cache, id)
+				// Remembered sessions last long enough to come back to correspondence games
+				sessionTime := SessionTime
+				if r.FormValue("remember") != "" {
+					sessionTime = RememberSessionTime
+				}
+
+				sessionID := GenerateSession(cache, id, sessionTime)
 				http.SetCookie(w, &http.Cookie{
 					Name:    "GameSession",
 					Value:   sessionID,
-					Expires: time.Now().Add(SessionTime),
+					Expires: time.Now().Add(sessionTime),
 				})
 				//w.Write([]byte("Authenticated " + sessionID))
 				http.Redirect(w, r, "/", 302)
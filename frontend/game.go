@@ -2,29 +2,41 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"math/rand"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-redis/redis"
 	"github.com/gorilla/websocket"
 )
 
-const GameBoardSize = 9
-
 // Coord defines a coordinate
 type Coord struct {
-	X   int
-	Y   int
-	Hit bool
+	X     int
+	Y     int
+	Hit   bool
+	State CellState
 }
 
 // Ship defines a ship
 type Ship struct {
+	ID       int
 	Size     int
 	Location []Coord
 	Sunk     bool
 }
 
+// Seat is a player's place in the turn order of a game. Eliminated players have lost
+// their fleet but keep their seat to watch the rest of the game. Players on the same
+// Team win or lose together, outside of team games every player has their own team
+type Seat struct {
+	PlayerID   int
+	Seat       int
+	Team       int
+	Eliminated bool
+}
+
 // GameBoard stores the state of the board
 type GameBoard struct {
 	Coords [][]Coord
@@ -57,21 +69,184 @@ const (
 
 	// ErrorEvent for generic errors
 	ErrorEvent EventName = 7
+
+	// ReplayEvent emitted from Client to Server asking for the state of a game after a move
+	ReplayEvent EventName = 8
+
+	// ReplayResultEvent emitted from Server to Client with the boards of a replayed game
+	ReplayResultEvent EventName = 9
+
+	// ResignEvent emitted from Client to Server when a player gives up the game
+	ResignEvent EventName = 10
+
+	// RematchOfferEvent emitted from Client to Server to offer a rematch after a game
+	// and from Server to Client to let the opponent know about the offer
+	RematchOfferEvent EventName = 11
+
+	// RematchAcceptEvent emitted from Client to Server to accept a rematch offer
+	RematchAcceptEvent EventName = 12
+
+	// AutoPlaceShipsEvent emitted from Client to Server asking the server to place the ships
+	AutoPlaceShipsEvent EventName = 13
+
+	// ShipsPlacedEvent emitted from Server to Client with a layout picked by the server for the Client to place
+	ShipsPlacedEvent EventName = 14
+
+	// BotOfferEvent emitted from Server to Client when no opponent was found in time.
+	// The Client answers with a JoinEvent for a bot game or a JoinEvent to keep waiting
+	BotOfferEvent EventName = 15
+
+	// UseAbilityEvent emitted from Client to Server to use an ability instead of firing
+	UseAbilityEvent EventName = 16
+
+	// AbilityResultEvent emitted from Server to Client with what an ability revealed
+	AbilityResultEvent EventName = 17
+
+	// RelocateShipEvent emitted from Client to Server to move or rotate a ship instead of firing
+	RelocateShipEvent EventName = 18
+
+	// ShipMovedEvent emitted from Server to Client letting the player know the opponent moved a ship
+	ShipMovedEvent EventName = 19
+
+	// TeamPingEvent emitted from Client to Server to point teammates at a location
+	// and from Server to Client to pass the ping on to the teammates only
+	TeamPingEvent EventName = 20
+
+	// CreateTournamentEvent emitted from Client to Server to organize a tournament
+	CreateTournamentEvent EventName = 21
+
+	// JoinTournamentEvent emitted from Client to Server to sign up to a tournament
+	JoinTournamentEvent EventName = 22
+
+	// StartTournamentEvent emitted from Client to Server when the organizer starts the tournament
+	StartTournamentEvent EventName = 23
+
+	// TournamentUpdateEvent emitted from Client to Server to ask for the state of a tournament
+	// and from Server to Client with the bracket and standings whenever the tournament changes
+	TournamentUpdateEvent EventName = 24
+
+	// CreateInviteEvent emitted from Client to Server to create a private game or challenge a user
+	CreateInviteEvent EventName = 25
+
+	// InviteCreatedEvent emitted from Server to Client with the code of the private game
+	// and again whenever someone takes a seat in it
+	InviteCreatedEvent EventName = 26
+
+	// ChallengeEvent emitted from Server to Client when another user challenges the player
+	ChallengeEvent EventName = 27
+
+	// AcceptInviteEvent emitted from Client to Server to join a private game with its code
+	AcceptInviteEvent EventName = 28
+
+	// CancelInviteEvent emitted from Client to Server to call off an invite or turn down a
+	// challenge and from Server to Client to let the others know
+	CancelInviteEvent EventName = 29
+
+	// WatchGameEvent emitted from Client to Server to start watching a live game
+	WatchGameEvent EventName = 30
+
+	// StopWatchingEvent emitted from Client to Server to stop watching a game
+	StopWatchingEvent EventName = 31
+
+	// SpectatorUpdateEvent emitted from Server to Client with the state of a watched game
+	SpectatorUpdateEvent EventName = 32
+
+	// LiveGamesEvent emitted from Client to Server to ask for the games worth watching
+	// and from Server to Client with the list
+	LiveGamesEvent EventName = 33
+
+	// ChatEvent emitted from Client to Server with a chat message for the game
+	// and from Server to Client to pass it on to the players
+	ChatEvent EventName = 34
+
+	// ChatHistoryEvent emitted from Client to Server to ask for the chat of a game
+	// and from Server to Client with every message sent so far
+	ChatHistoryEvent EventName = 35
+
+	// MuteEvent emitted from Client to Server to mute or unmute chat from a player
+	MuteEvent EventName = 36
+
+	// GameRecordEvent emitted from Client to Server to ask for the record of a game
+	// and from Server to Client with the record written in the notation
+	GameRecordEvent EventName = 37
+
+	// ImportRecordEvent emitted from Client to Server with a record to replay. The Server
+	// answers with a ReplayResultEvent
+	ImportRecordEvent EventName = 38
+
+	// CorrespondenceGamesEvent emitted from Client to Server to ask for the player's correspondence
+	// games and from Server to Client with the list
+	CorrespondenceGamesEvent EventName = 39
+
+	// OpenGameEvent emitted from Client to Server to pick a running game back up
+	OpenGameEvent EventName = 40
 )
 
+// JoinEventMessage is sent by the Client to find a game. Bot asks for a game against a bot
+// of that difficulty instead of waiting for another player. RuleSet picks the rules to play
+// with and MoveDays asks for a correspondence game, players are only paired with others who
+// asked for the same rules and days for a move. Bots only play live games
+type JoinEventMessage struct {
+	EventMessage
+	Bot      BotDifficulty
+	RuleSet  string
+	MoveDays int
+}
+
+// GameStartedEventMessage lets the players know the game has been created. Players are
+// seated in the order given and take their turns in that order. MoveDays is set for correspondence games
 type GameStartedEventMessage struct {
-	GameID int
+	GameID   int
+	Deadline *time.Time
+	Rules    RuleSet
+	Players  []int
+	MoveDays int
 }
 
+// PlaceShipsEventMessage is sent by the Client with the fleet and, when the rule set has
+// mines, the locations of the mines
 type PlaceShipsEventMessage struct {
 	EventMessage
 	Ships []Ship
+	Mines []Coord
+}
+
+// AutoPlaceShipsEventMessage asks for a random fleet. The same Seed always gives the same fleet
+type AutoPlaceShipsEventMessage struct {
+	EventMessage
+	Seed *int64
 }
 
+// ShipsPlacedEventMessage is the fleet and mines picked by the server along with the seed used to pick them
+type ShipsPlacedEventMessage struct {
+	Ships []Ship
+	Mines []Coord
+	Seed  int64
+}
+
+/*
+GameUpdateEventMessage is the state of the game for a player. Shots is how many
+shots the player fires when it is their turn and Abilities how many uses of each
+ability they have left. Opponents has what the player knows of every fleet on the
+other teams and HitBoard is the board of the next opponent in the turn order, the
+only opponent in a two player game. Teammates has the full boards of the player's team
+*/
 type GameUpdateEventMessage struct {
-	MyBoard  GameBoard
-	HitBoard GameBoard
-	Status   GameState
+	MyBoard   GameBoard
+	HitBoard  GameBoard
+	Opponents []OpponentBoard
+	Teammates []PlayerBoard
+	Status    GameState
+	Deadline  *time.Time
+	Shots     int
+	Abilities map[Ability]int
+}
+
+// OpponentBoard is what a player knows of the fleet of one of the other players
+type OpponentBoard struct {
+	PlayerID   int
+	Eliminated bool
+	Board      GameBoard
 }
 
 type GameState int
@@ -81,173 +256,930 @@ const (
 	GameStateLost      GameState = 2
 	GameStateMyTurn    GameState = 3
 	GameStateNotMyTurn GameState = 4
+
+	// GameStateAbandoned is a game that ended without a winner as nobody placed their ships in time
+	GameStateAbandoned GameState = 5
 )
 
+// MakeMoveEventMessage is sent by the Client to fire at the opponent. In Salvo games
+// every shot of the volley is sent in Locations. Target is the player fired at, when it
+// is not set the shots are fired at the next player in the turn order
 type MakeMoveEventMessage struct {
-	Location Coord
+	EventMessage
+	Location  Coord
+	Locations []Coord
+	Target    int
+}
+
+// targets lists every location fired at by the move
+func (m MakeMoveEventMessage) targets() []Coord {
+	if len(m.Locations) > 0 {
+		return m.Locations
+	}
+
+	return []Coord{m.Location}
+}
+
+/*
+MoveResultEventMessage is sent to every player after a move is resolved.
+Outcome is from the point of view of the player receiving the message and is the
+best outcome of the volley. Volley has the outcome of every shot in the volley. Ability
+is set when the shots came from an ability. Shooter fired at the fleet of Target and
+Eliminated is set when the move sank the last ship of a player. The boards, Shots
+and Abilities are as in GameUpdateEventMessage
+*/
+type MoveResultEventMessage struct {
+	Shooter    int
+	Target     int
+	Eliminated int
+	Location   Coord
+	Outcome    MoveOutcome
+	Ability    Ability
+	Volley     []ShotResult
+	MyBoard    GameBoard
+	HitBoard   GameBoard
+	Opponents  []OpponentBoard
+	Teammates  []PlayerBoard
+	Status     GameState
+	Shots      int
+	Abilities  map[Ability]int
 }
 
-// type MoveResultEventMessage struct {
-// 	Location Coord
-// 	Outcome  MoveOutcome
-// 	MyBoard  GameBoard
-// 	HitBoard GameBoard
-// }
+// ShotResult is the outcome of a single shot in a volley. When the shot hits a mine
+// Revealed is the location of the shooter's ship given away to the opponent
+type ShotResult struct {
+	Location Coord
+	Outcome  MoveOutcome
+	Revealed *Coord
+}
 
-// type MoveOutcome int
+type MoveOutcome int
 
-// const (
-// 	OutcomeWon      MoveOutcome = 1
-// 	OutcomeLost     MoveOutcome = 2
-// 	OutcomeShipSunk MoveOutcome = 3
-// 	OutcomeShipHit  MoveOutcome = 4
-// 	OutcomeShipMiss MoveOutcome = 5
-// )
+const (
+	OutcomeWon      MoveOutcome = 1
+	OutcomeLost     MoveOutcome = 2
+	OutcomeShipSunk MoveOutcome = 3
+	OutcomeShipHit  MoveOutcome = 4
+	OutcomeShipMiss MoveOutcome = 5
+	OutcomeMineHit  MoveOutcome = 6
+)
 
 type ErrorEventMessage struct {
-	Err string
+	Err        string
+	Violations []string
 }
 
 // ConstructGameUpdateMessage constructs the state of the game from the database for the player
-func ConstructGameUpdateMessage(db *sql.DB, gameID int, playerID int, turn bool) GameUpdateEventMessage {
+func ConstructGameUpdateMessage(db *sql.DB, gameID int, playerID int) GameUpdateEventMessage {
 
 	var result GameUpdateEventMessage
 	status, winner := FindGameState(db, gameID)
+	turn := FindTurnForGame(db, gameID) == playerID
+	rules := FindRuleSetForGame(db, gameID)
+	seats := FindSeatsForGame(db, gameID)
 
-	if status == "Completed" {
-		if winner == playerID {
+	if status == "Abandoned" {
+		result.Status = GameStateAbandoned
+	} else if status != "Started" {
+		// The whole team shares the win
+		if winner == playerID || (winner != 0 && teamOf(seats, winner) == teamOf(seats, playerID)) {
 			result.Status = GameStateWon
 		} else {
 			result.Status = GameStateLost
 		}
+	} else if isEliminated(seats, playerID) {
+		// Eliminated players watch the rest of the game
+		result.Status = GameStateLost
+		result.Deadline = FindClockForGame(db, gameID).deadline()
 	} else {
 		if turn {
 			result.Status = GameStateMyTurn
+			result.Shots = shotsForTurn(db, gameID, playerID, rules)
 		} else {
 			result.Status = GameStateNotMyTurn
 		}
+
+		result.Deadline = FindClockForGame(db, gameID).deadline()
 	}
 
-	ships := FindShipsForPlayer(db, gameID, playerID)
+	result.Abilities = remainingAbilities(db, gameID, playerID, rules)
 
-	// Populate My Board
-	for i := 0; i < GameBoardSize; i++ {
+	// Populate My Board with every shot fired at the player
+	result.MyBoard = buildPlayerBoard(db, gameID, playerID, rules)
 
-		var row []Coord
+	team := teamOf(seats, playerID)
+	targetID := nextOpponent(seats, playerID)
 
-		for j := 0; j < GameBoardSize; j++ {
+	for _, seat := range seats {
+		if seat.PlayerID == playerID {
+			continue
+		}
 
-			locationAdded := false
+		// Teammates see each other's fleets
+		if seat.Team == team {
+			result.Teammates = append(result.Teammates, PlayerBoard{
+				PlayerID: seat.PlayerID,
+				Board:    buildPlayerBoard(db, gameID, seat.PlayerID, rules),
+			})
+			continue
+		}
 
-			for _, ship := range ships {
-				for _, location := range ship.Location {
-					if location.X == i && location.Y == j {
-						row = append(row, location)
-						locationAdded = true
-					}
-				}
+		board := buildOpponentBoard(db, gameID, seat.PlayerID, rules)
+
+		result.Opponents = append(result.Opponents, OpponentBoard{
+			PlayerID:   seat.PlayerID,
+			Eliminated: seat.Eliminated,
+			Board:      board,
+		})
+
+		if seat.PlayerID == targetID {
+			result.HitBoard = board
+		}
+	}
+
+	return result
+}
+
+// buildPlayerBoard populates the full board of the player with their ships, mines and every shot fired at them
+func buildPlayerBoard(db *sql.DB, gameID int, playerID int, rules RuleSet) GameBoard {
+
+	board := buildMyBoard(rules.BoardSize, FindShipsForPlayer(db, gameID, playerID), FindShotsAtPlayer(db, gameID, playerID))
+	markMines(board, FindMinesForPlayer(db, gameID, playerID))
+	markIslands(board, rules.Islands)
+
+	return board
+}
+
+// buildOpponentBoard populates the board of the opponent with only what has been found out by
+// firing at them along with any ship locations the opponent gave away on a mine
+func buildOpponentBoard(db *sql.DB, gameID int, opponentID int, rules RuleSet) GameBoard {
+
+	shots := FindShotsAtPlayer(db, gameID, opponentID)
+
+	board := buildHitBoard(rules.BoardSize, FindShipsForPlayer(db, gameID, opponentID), shots)
+	markMines(board, minesFiredAt(FindMinesForPlayer(db, gameID, opponentID), shots))
+	markIslands(board, rules.Islands)
+	markRevealed(board, FindRevealsForPlayer(db, gameID, opponentID))
+
+	return board
+}
+
+/*
+nextPlayer finds the player after the player in the turn order, skipping anyone who has been
+eliminated. Once every other player has been eliminated the player in the next seat is returned
+so a finished game still has an opponent. Returns -1 if the player is alone in the game
+*/
+func nextPlayer(seats []Seat, playerID int) int {
+	return nextSeat(seats, playerID, false)
+}
+
+// nextOpponent finds the next player in the turn order on another team, as in nextPlayer
+func nextOpponent(seats []Seat, playerID int) int {
+	return nextSeat(seats, playerID, true)
+}
+
+// nextSeat finds the next player after the player who has not been eliminated, only looking at
+// other teams if asked to. Eliminated players are only returned when nobody else is left
+func nextSeat(seats []Seat, playerID int, otherTeam bool) int {
+
+	start, team := 0, -1
+	for i, seat := range seats {
+		if seat.PlayerID == playerID {
+			start, team = i, seat.Team
+		}
+	}
+
+	for _, eliminated := range []bool{false, true} {
+		for i := 1; i < len(seats); i++ {
+			seat := seats[(start+i)%len(seats)]
+
+			if otherTeam && seat.Team == team {
+				continue
 			}
 
-			if !locationAdded {
-				row = append(row, Coord{i, j, true})
+			if eliminated || !seat.Eliminated {
+				return seat.PlayerID
 			}
 		}
+	}
+
+	return -1
+}
+
+// targetFor checks the player can fire at the target and returns the player fired at. No
+// target is the next opponent in the turn order. Returns -1 if the target is not an opponent
+// still in the game
+func targetFor(seats []Seat, playerID int, target int) int {
+
+	if target == 0 {
+		return nextOpponent(seats, playerID)
+	}
+
+	team := teamOf(seats, playerID)
+
+	for _, seat := range seats {
+		if seat.PlayerID == target && seat.Team != team && !seat.Eliminated {
+			return target
+		}
+	}
+
+	return -1
+}
+
+// activePlayers lists the players who have not been eliminated in the order they are seated
+func activePlayers(seats []Seat) []int {
+
+	var players []int
+
+	for _, seat := range seats {
+		if !seat.Eliminated {
+			players = append(players, seat.PlayerID)
+		}
+	}
+
+	return players
+}
 
-		result.MyBoard.Coords = append(result.MyBoard.Coords, row)
+// teamOf finds the team of the player. Returns -1 if the player is not seated in the game
+func teamOf(seats []Seat, playerID int) int {
+
+	for _, seat := range seats {
+		if seat.PlayerID == playerID {
+			return seat.Team
+		}
+	}
+
+	return -1
+}
+
+// teamsLeft counts the teams with at least one player who has not been eliminated
+func teamsLeft(seats []Seat) int {
+
+	teams := make(map[int]bool)
+
+	for _, seat := range seats {
+		if !seat.Eliminated {
+			teams[seat.Team] = true
+		}
+	}
+
+	return len(teams)
+}
+
+// isEliminated checks if the player has been knocked out of the game
+func isEliminated(seats []Seat, playerID int) bool {
+
+	for _, seat := range seats {
+		if seat.PlayerID == playerID {
+			return seat.Eliminated
+		}
+	}
+
+	return false
+}
+
+// eliminateSeat copies the seats with the player marked as eliminated
+func eliminateSeat(seats []Seat, playerID int) []Seat {
+
+	result := make([]Seat, len(seats))
+	copy(result, seats)
+
+	for i := range result {
+		if result[i].PlayerID == playerID {
+			result[i].Eliminated = true
+		}
 	}
 
 	return result
 }
 
 /*
-JoinGame checks redis to see if anyone is waiting for a game
-if someone is then it pairs the two people and then creates a game
+JoinGame checks redis to see if enough people are waiting for a game with the same rules
+to fill every seat. If they are then it seats them together and then creates a game
 Once the game is created then it informs the other sockets via Kafka
 */
-func JoinGame(db *sql.DB, client *redis.Client, producer *kafka.Producer, conn *websocket.Conn, userID int) {
+func JoinGame(db *sql.DB, client *redis.Client, producer *kafka.Producer, conn *websocket.Conn, message JoinEventMessage, userID int) {
+
+	rules, ok := FindRuleSet(message.RuleSet)
+	if !ok {
+		PublishErrorEvent(producer, "Unknown rule set", userID, 0)
+		return
+	}
+
+	if !isValidMoveDays(message.MoveDays) {
+		PublishErrorEvent(producer, fmt.Sprintf("Correspondence games can have at most %d days for a move", maxMoveDays), userID, 0)
+		return
+	}
+
+	if message.Bot != "" {
+		StartBotGame(db, client, producer, message.Bot, rules.Name, userID)
+		return
+	}
 
-	// Check Redis to see if someone is waiting
-	userWaiting := CheckIfSomeOneIsWaiting(client)
+	// Check Redis to see if enough people are waiting for the same rules and days for a move
+	usersWaiting := FindPlayersWaiting(client, rules.Name, message.MoveDays, rules.seats()-1, userID)
 
-	// ---- If no one is waiting then add to cache
-	if userWaiting == -1 {
-		AddToEndOfQueue(client, userID)
+	// ---- If not enough people are waiting then add to cache
+	if usersWaiting == nil {
+		AddToEndOfQueue(client, rules.Name, message.MoveDays, userID)
 		return
 	}
 
-	// ---- If someone is waiting
-	// -------- Pop person out of redis - Done in CheckIfSomeOneIsWaiting
+	// ---- If enough people are waiting
+	// -------- Pop them out of redis - Done in FindPlayersWaiting
 
 	// -------- Create a game in postgres
-	gameID := CreateNewGame(db, userID, userWaiting)
+	players := append([]int{userID}, usersWaiting...)
 
-	gameUpdateMessagePlayer1 := EventMessage{
-		Event: GameStartedEvent,
-		To:    userID,
-		Payload: GameStartedEventMessage{
-			GameID: gameID,
-		},
+	gameID, err := createGame(db, players, rules.Name, message.MoveDays, false)
+	if err != nil {
+		for _, playerID := range players {
+			PublishErrorEvent(producer, "Could not create game", playerID, 0)
+		}
+		return
 	}
 
-	gameUpdateMessagePlayer1.Send(producer)
+	PublishGameStarted(db, producer, gameID)
+}
 
-	gameUpdateMessagePlayer2 := EventMessage{
-		Event: GameStartedEvent,
-		Payload: GameStartedEventMessage{
-			GameID: gameID,
-		},
-		To: userWaiting,
+// PublishGameStarted lets every player know the game has been created and they should place their ships
+func PublishGameStarted(db *sql.DB, producer *kafka.Producer, gameID int) {
+
+	started := gameStarted(db, gameID)
+
+	for _, playerID := range started.Players {
+		gameStartedMessage := EventMessage{
+			Event:   GameStartedEvent,
+			To:      playerID,
+			GameID:  gameID,
+			Payload: started,
+		}
+
+		gameStartedMessage.Send(producer)
+	}
+}
+
+// gameStarted builds the message the players are sent when the game starts
+func gameStarted(db *sql.DB, gameID int) GameStartedEventMessage {
+
+	clock := FindClockForGame(db, gameID)
+
+	started := GameStartedEventMessage{
+		GameID:   gameID,
+		Deadline: clock.deadline(),
+		Rules:    FindRuleSetForGame(db, gameID),
+		MoveDays: clock.MoveDays,
+	}
+
+	for _, seat := range FindSeatsForGame(db, gameID) {
+		started.Players = append(started.Players, seat.PlayerID)
 	}
 
-	gameUpdateMessagePlayer2.Send(producer)
+	return started
 }
 
-// PlaceShips places the ships on the board and randomly emits a player who will start
+// PlaceShips places the ships on the board of the game and randomly emits a player who will start
 func PlaceShips(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message PlaceShipsEventMessage, userID int) {
 
-	// Look for the latest game by this player
-	gameID := FindLatestGameForPlayer(db, userID)
+	gameID := message.GameID
 
-	if gameID == -1 {
-		PublishErrorEvent(producer, "Could not find game", userID)
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
 		return
 	}
 
+	placeFleet(db, producer, gameID, userID, message.Ships, message.Mines)
+}
+
+/*
+placeFleet validates and stores the fleet and mines of a player and starts the game once every
+player has placed their ships. Returns false if the fleet could not be placed
+*/
+func placeFleet(db *sql.DB, producer *kafka.Producer, gameID int, userID int, ships []Ship, mines []Coord) bool {
+
+	if len(FindShipsForPlayer(db, gameID, userID)) > 0 {
+		PublishErrorEvent(producer, "Ships have already been placed", userID, gameID)
+		return false
+	}
+
+	// Validate the fleet before anything is stored
+	rules := FindRuleSetForGame(db, gameID)
+	violations := append(ValidateFleet(rules, ships), ValidateMines(rules, ships, mines)...)
+
+	if len(violations) > 0 {
+		PublishValidationErrorEvent(producer, "Invalid ship placement", violations, userID, gameID)
+		return false
+	}
+
 	// Create Ships in Database
-	err := CreateShipsInDatabase(db, userID, gameID, message.Ships)
+	err := CreateShipsInDatabase(db, userID, gameID, ships, mines)
 
 	if err != nil {
-		PublishErrorEvent(producer, err.Error(), userID)
+		PublishErrorEvent(producer, err.Error(), userID, gameID)
+		return false
+	}
+
+	// If every player has placed ships
+	if HaveAllPlayersPlacedShips(db, gameID) {
+		startGame(db, producer, gameID)
+	}
+
+	return true
+}
+
+// startGame picks a random player to move first once every fleet is placed and starts their clock
+func startGame(db *sql.DB, producer *kafka.Producer, gameID int) {
+
+	// -- Pick a random player
+	players := activePlayers(FindSeatsForGame(db, gameID))
+	playerID := players[rand.Intn(len(players))]
+
+	// -- Store the turn. If the last players placed their ships at the same time
+	// -- only the first one to set the turn emits the updates
+	now := time.Now()
+	deadline := FindClockForGame(db, gameID).turnDeadline(now, playerID)
+
+	if !SetFirstTurnForGame(db, gameID, playerID, deadline, now) {
 		return
 	}
 
-	// If Both Players have placed ships
-	if HaveBothPlayersPlacedShips(db, gameID) {
-		// -- Pick a random player
-		randomPlayer := rand.Intn(2)
-		playerID := getPlayerForGame(db, gameID, randomPlayer)
+	// -- Emit the Game Update Event to every player
+	PublishGameUpdates(db, producer, gameID)
+
+	playBotTurn(db, producer, gameID)
+}
+
+// PublishGameUpdates sends the current state of the game to every player, including those eliminated
+func PublishGameUpdates(db *sql.DB, producer *kafka.Producer, gameID int) {
+
+	for _, seat := range FindSeatsForGame(db, gameID) {
+		playerID := seat.PlayerID
 
-		// -- Emit the Your Turn Event for that player
 		gameUpdateMessagePlayer := EventMessage{
-			Event: GameUpdateEvent,
-			To:    playerID,
+			Event:   GameUpdateEvent,
+			To:      playerID,
+			GameID:  gameID,
+			Payload: ConstructGameUpdateMessage(db, gameID, playerID),
 		}
 
 		gameUpdateMessagePlayer.Send(producer)
 	}
+
+	PublishSpectatorUpdate(db, producer, gameID)
 }
 
 /*
-PublishErrorEvent sends an error message to a client
+MakeMove resolves the shots fired by a player against the target's ships.
+The hit is recorded against the ship, the ship is marked as sunk once every
+location has been hit and the target is eliminated once every ship is sunk.
+The game is completed when only one fleet is left afloat.
+Every player is then sent the result of the move
 */
-func PublishErrorEvent(producer *kafka.Producer, err string, playerID int) {
+func MakeMove(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message MakeMoveEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	makeMove(db, producer, gameID, message.targets(), message.Target, userID)
+}
+
+/*
+makeMove plays a volley of shots for the player in the game at the fleet of the target.
+Outside of Salvo games a volley is a single shot. Humans and bots both move through here
+*/
+func makeMove(db *sql.DB, producer *kafka.Producer, gameID int, targets []Coord, target int, userID int) {
+
+	if !checkTurn(db, producer, gameID, userID) {
+		return
+	}
+
+	targetID := targetFor(FindSeatsForGame(db, gameID), userID, target)
+	if targetID == -1 {
+		PublishErrorEvent(producer, "That player can not be fired at", userID, gameID)
+		return
+	}
+
+	rules := FindRuleSetForGame(db, gameID)
+	fired := FindShotsAtPlayer(db, gameID, targetID)
+
+	// A volley can be smaller only when there are not enough locations left to fire at
+	shots := shotsForTurn(db, gameID, userID, rules)
+	if left := rules.BoardSize*rules.BoardSize - len(rules.Islands) - len(fired); left < shots {
+		shots = left
+	}
+
+	if len(targets) != shots {
+		PublishErrorEvent(producer, fmt.Sprintf("You must fire %d shots", shots), userID, gameID)
+		return
+	}
+
+	firedAt := make(map[Coord]bool)
+	for _, shot := range fired {
+		firedAt[Coord{X: shot.X, Y: shot.Y}] = true
+	}
+
+	for _, target := range targets {
+		if !rules.isOnBoard(target) {
+			PublishErrorEvent(producer, "Move is outside the board", userID, gameID)
+			return
+		}
+
+		if rules.isIsland(target) {
+			PublishErrorEvent(producer, "Location is an island", userID, gameID)
+			return
+		}
+
+		if firedAt[Coord{X: target.X, Y: target.Y}] {
+			PublishErrorEvent(producer, "Location has already been fired at", userID, gameID)
+			return
+		}
+
+		firedAt[Coord{X: target.X, Y: target.Y}] = true
+	}
+
+	playVolley(db, producer, gameID, userID, targetID, targets[0], targets, "")
+}
+
+// checkTurn checks the game is running and it is the player's turn. The player is sent an error if not
+func checkTurn(db *sql.DB, producer *kafka.Producer, gameID int, userID int) bool {
+
+	status, _ := FindGameState(db, gameID)
+	if status != "Started" {
+		PublishErrorEvent(producer, "Game is not in progress", userID, gameID)
+		return false
+	}
+
+	if !HaveAllPlayersPlacedShips(db, gameID) {
+		PublishErrorEvent(producer, "Not every player has placed their ships", userID, gameID)
+		return false
+	}
+
+	if FindTurnForGame(db, gameID) != userID {
+		PublishErrorEvent(producer, "It is not your turn", userID, gameID)
+		return false
+	}
+
+	return true
+}
+
+/*
+playVolley resolves the shots against the fleet of the target, hands the turn to the next player
+and sends every player the result. The targets must already be checked. When the shots come
+from an ability the use is recorded against the move. An ability may fire no shots at all
+*/
+func playVolley(db *sql.DB, producer *kafka.Producer, gameID int, userID int, targetID int, location Coord, targets []Coord, ability Ability) {
+
+	seats := FindSeatsForGame(db, gameID)
+	ships := FindShipsForPlayer(db, gameID, targetID)
+
+	mines := FindMinesForPlayer(db, gameID, targetID)
+	ownShips := FindShipsForPlayer(db, gameID, userID)
+	revealed := FindRevealsForPlayer(db, gameID, userID)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// Every shot in the volley is resolved before any of them are reported
+	var results []ShotResult
+	var hits [][2]int
+
+	for _, target := range targets {
+		outcome, shipIndex, locationIndex := resolveShot(ships, target)
+		result := ShotResult{Location: target, Outcome: outcome}
+
+		if shipIndex != -1 {
+			hits = append(hits, [2]int{shipIndex, locationIndex})
+		}
+
+		// Mines are never under a ship so a shot at a mine has missed
+		if containsCoord(mines, target) {
+			result.Outcome = OutcomeMineHit
+			result.Revealed = mineRevealFor(r, ownShips, revealed)
+
+			if result.Revealed != nil {
+				revealed = append(revealed, *result.Revealed)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	outcome := volleyOutcome(results)
+
+	// Sinking the last ship of a fleet eliminates the target. The game is only won
+	// once every fleet on the other teams is gone
+	eliminated := 0
+	if outcome == OutcomeWon {
+		eliminated = targetID
+		seats = eliminateSeat(seats, targetID)
+
+		if teamsLeft(seats) > 1 {
+			outcome = OutcomeShipSunk
+			for i := range results {
+				if results[i].Outcome == OutcomeWon {
+					results[i].Outcome = OutcomeShipSunk
+				}
+			}
+		}
+	}
+
+	// Hand the turn to the next player. This only succeeds if it is still this player's turn
+	// so two moves sent at the same time through different servers cannot both be played.
+	// The volley is recorded in the same transaction so it is never half written
+	nextID := nextPlayer(seats, userID)
+	now := time.Now()
+	deadline := FindClockForGame(db, gameID).turnDeadline(now, nextID)
+
+	move := MoveResultEventMessage{
+		Shooter:    userID,
+		Target:     targetID,
+		Eliminated: eliminated,
+		Location:   location,
+		Outcome:    outcome,
+		Ability:    ability,
+		Volley:     results,
+	}
+
+	moveNumber, err := PlayMoveInDatabase(db, gameID, userID, nextID, deadline, now, func(tx *sql.Tx, number int) error {
+		return recordVolleyInTx(tx, gameID, number, move, ships, hits)
+	})
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, gameID)
+		return
+	}
+	if moveNumber == -1 {
+		PublishErrorEvent(producer, "It is not your turn", userID, gameID)
+		return
+	}
+
+	publishMoveResult(db, producer, gameID, move, userID)
+
+	// The other teams see the winning shot as the shot they lost to
+	lost := move
+	lost.Volley = nil
+
+	for _, result := range results {
+		if result.Outcome == OutcomeWon {
+			result.Outcome = OutcomeLost
+		}
+		lost.Volley = append(lost.Volley, result)
+	}
+
+	if outcome == OutcomeWon {
+		lost.Outcome = OutcomeLost
+	}
+
+	team := teamOf(seats, userID)
+
+	for _, seat := range seats {
+		if seat.PlayerID == userID {
+			continue
+		}
+
+		if seat.Team == team {
+			publishMoveResult(db, producer, gameID, move, seat.PlayerID)
+		} else {
+			publishMoveResult(db, producer, gameID, lost, seat.PlayerID)
+		}
+	}
+
+	PublishSpectatorUpdate(db, producer, gameID)
+
+	playBotTurn(db, producer, gameID)
+}
+
+// recordVolleyInTx writes every shot of the volley along with the ships it hit and sunk,
+// and the player it eliminated or the game it won
+func recordVolleyInTx(tx *sql.Tx, gameID int, moveNumber int, move MoveResultEventMessage, ships []Ship, hits [][2]int) error {
+
+	if move.Ability != "" {
+		err := recordAbilityUseInTx(tx, gameID, move.Shooter, move.Ability, moveNumber, move.Location)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, result := range move.Volley {
+		err := recordMoveInTx(tx, gameID, Move{
+			Number:   moveNumber,
+			Shot:     i,
+			Kind:     MoveShot,
+			PlayerID: move.Shooter,
+			Target:   move.Target,
+			Location: result.Location,
+			Outcome:  result.Outcome,
+		})
+		if err != nil {
+			return err
+		}
+
+		if result.Revealed != nil {
+			err = recordRevealInTx(tx, gameID, move.Shooter, moveNumber, *result.Revealed)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, hit := range hits {
+		err := markShipLocationHitInTx(tx, ships[hit[0]].ID, hit[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ship := range ships {
+		if ship.Sunk {
+			err := markShipSunkInTx(tx, ship.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if move.Eliminated != 0 {
+		err := eliminatePlayerInTx(tx, gameID, move.Eliminated)
+		if err != nil {
+			return err
+		}
+	}
+
+	if move.Outcome == OutcomeWon {
+		return completeGameInTx(tx, gameID, move.Shooter)
+	}
+
+	return nil
+}
+
+// shotsForTurn is how many shots the player fires on their turn. In Salvo games
+// this is the number of their ships still afloat
+func shotsForTurn(db *sql.DB, gameID int, playerID int, rules RuleSet) int {
+
+	if !rules.Salvo {
+		return 1
+	}
+
+	return CountShipsAfloat(db, gameID, playerID)
+}
+
+// mineRevealFor picks one of the player's ship locations which has not been hit or revealed
+// to give away to the opponent. Returns nil if there is nothing left to reveal
+func mineRevealFor(r *rand.Rand, ships []Ship, revealed []Coord) *Coord {
+
+	var hidden []Coord
+
+	for _, ship := range ships {
+		for _, location := range ship.Location {
+			if !location.Hit && !containsCoord(revealed, location) {
+				hidden = append(hidden, Coord{X: location.X, Y: location.Y})
+			}
+		}
+	}
+
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	return &hidden[r.Intn(len(hidden))]
+}
+
+// volleyOutcome is the best outcome of any shot in the volley
+func volleyOutcome(results []ShotResult) MoveOutcome {
+
+	outcome := OutcomeShipMiss
+
+	// Outcomes are numbered from best to worst
+	for _, result := range results {
+		if result.Outcome < outcome {
+			outcome = result.Outcome
+		}
+	}
+
+	return outcome
+}
+
+// publishMoveResult sends the result of a move along with the latest state of the boards to a player
+func publishMoveResult(db *sql.DB, producer *kafka.Producer, gameID int, move MoveResultEventMessage, playerID int) {
+
+	update := ConstructGameUpdateMessage(db, gameID, playerID)
+
+	move.MyBoard = update.MyBoard
+	move.HitBoard = update.HitBoard
+	move.Opponents = update.Opponents
+	move.Teammates = update.Teammates
+	move.Status = update.Status
+	move.Shots = update.Shots
+	move.Abilities = update.Abilities
+
+	moveResultMessage := EventMessage{
+		Event:   MoveResultEvent,
+		To:      playerID,
+		GameID:  gameID,
+		Payload: move,
+	}
+
+	moveResultMessage.Send(producer)
+}
+
+/*
+resolveShot works out the outcome of a shot against the fleet and marks the hit
+and any sunk ship on the fleet. The index of the ship and of the location within
+the ship are returned, or -1, -1 if the shot missed
+*/
+func resolveShot(ships []Ship, target Coord) (MoveOutcome, int, int) {
+
+	shipIndex, locationIndex := findShipAtLocation(ships, target)
+
+	if shipIndex == -1 {
+		return OutcomeShipMiss, -1, -1
+	}
+
+	ship := &ships[shipIndex]
+	ship.Location[locationIndex].Hit = true
+
+	if !isShipSunk(*ship) {
+		return OutcomeShipHit, shipIndex, locationIndex
+	}
+
+	ship.Sunk = true
+
+	if areAllShipsSunk(ships) {
+		return OutcomeWon, shipIndex, locationIndex
+	}
+
+	return OutcomeShipSunk, shipIndex, locationIndex
+}
+
+// findShipAtLocation returns the index of the ship and the index of the location within the ship
+// for the coordinate. If there is no ship at the coordinate then -1, -1 is returned
+func findShipAtLocation(ships []Ship, target Coord) (int, int) {
+	for i, ship := range ships {
+		for j, location := range ship.Location {
+			if location.X == target.X && location.Y == target.Y {
+				return i, j
+			}
+		}
+	}
+
+	return -1, -1
+}
+
+// isShipSunk checks if every location of the ship has been hit
+func isShipSunk(ship Ship) bool {
+	for _, location := range ship.Location {
+		if !location.Hit {
+			return false
+		}
+	}
+
+	return true
+}
+
+// areAllShipsSunk checks if every ship in the fleet has been sunk
+func areAllShipsSunk(ships []Ship) bool {
+	for _, ship := range ships {
+		if !ship.Sunk {
+			return false
+		}
+	}
+
+	return len(ships) > 0
+}
+
+/*
+PublishErrorEvent sends an error message to a client. GameID is the game the error is
+about, 0 when it is not about a game
+*/
+func PublishErrorEvent(producer *kafka.Producer, err string, playerID int, gameID int) {
 
 	gameUpdateMessage := EventMessage{
 		Event: ErrorEvent,
 		Payload: ErrorEventMessage{
 			Err: err,
 		},
-		To: playerID,
+		To:     playerID,
+		GameID: gameID,
+	}
+
+	gameUpdateMessage.Send(producer)
+}
+
+/*
+PublishValidationErrorEvent sends an error message to a client along with every rule
+that was broken
+*/
+func PublishValidationErrorEvent(producer *kafka.Producer, err string, violations []string, playerID int, gameID int) {
+
+	gameUpdateMessage := EventMessage{
+		Event: ErrorEvent,
+		Payload: ErrorEventMessage{
+			Err:        err,
+			Violations: violations,
+		},
+		To:     playerID,
+		GameID: gameID,
 	}
 
 	gameUpdateMessage.Send(producer)
@@ -14,24 +14,52 @@ var db *sql.DB
 
 func TestConstructGameUpdateMessage(t *testing.T) {
 
+	// Player 2 has hit the cruiser of Player 1 once and missed once
+	player1Board := constructBoard(CellEmpty, []Coord{
+		Coord{X: 0, Y: 1, Hit: true, State: CellHit}, Coord{X: 0, Y: 2, State: CellShip}, Coord{X: 0, Y: 3, State: CellShip},
+		Coord{X: 1, Y: 1, State: CellShip}, Coord{X: 2, Y: 1, State: CellShip},
+		Coord{X: 5, Y: 5, Hit: true, State: CellMiss},
+	})
+
+	// Player 1 has sunk the destroyer of Player 2 and missed once
+	player2Board := constructBoard(CellEmpty, []Coord{
+		Coord{X: 0, Y: 1, State: CellShip}, Coord{X: 0, Y: 2, State: CellShip}, Coord{X: 0, Y: 3, State: CellShip},
+		Coord{X: 1, Y: 1, Hit: true, State: CellSunk}, Coord{X: 2, Y: 1, Hit: true, State: CellSunk},
+		Coord{X: 4, Y: 4, Hit: true, State: CellMiss},
+	})
+
+	player1HitBoard := constructBoard(CellUnknown, []Coord{
+		Coord{X: 1, Y: 1, Hit: true, State: CellSunk}, Coord{X: 2, Y: 1, Hit: true, State: CellSunk},
+		Coord{X: 4, Y: 4, Hit: true, State: CellMiss},
+	})
+
+	player2HitBoard := constructBoard(CellUnknown, []Coord{
+		Coord{X: 0, Y: 1, Hit: true, State: CellHit},
+		Coord{X: 5, Y: 5, Hit: true, State: CellMiss},
+	})
+
+	emptyBoard := constructBoard(CellEmpty, nil)
+	unknownBoard := constructBoard(CellUnknown, nil)
+
 	tt := []struct {
-		name            string
-		gameID          int
-		playerID        int
-		turn            bool
-		expectedStatus  GameState
-		expectedMyState GameBoard
-		// opponentState  GameBoard
+		name             string
+		gameID           int
+		playerID         int
+		expectedStatus   GameState
+		expectedMyState  GameBoard
+		expectedHitState GameBoard
 	}{
-		{"When game is won", 2, 1, true, GameStateWon, constructCoords([]Coord{Coord{0, 1, false}, Coord{0, 2, false}, Coord{0, 3, false}, Coord{1, 1, false}, Coord{2, 1, false}})},
-		{"When game is lost", 3, 1, true, GameStateLost, constructCoords([]Coord{Coord{0, 1, false}, Coord{0, 2, false}, Coord{0, 3, false}, Coord{1, 1, false}, Coord{2, 1, false}})},
-		{"When game is running and its players turn", 1, 1, true, GameStateMyTurn, constructCoords([]Coord{Coord{0, 1, false}, Coord{0, 2, false}, Coord{0, 3, false}, Coord{1, 1, false}, Coord{2, 1, false}})},
-		{"When game is running and its not the players turn", 1, 1, false, GameStateNotMyTurn, constructCoords([]Coord{Coord{0, 1, false}, Coord{0, 2, false}, Coord{0, 3, false}, Coord{1, 1, false}, Coord{2, 1, false}})},
+		{"When game is won", 2, 1, GameStateWon, emptyBoard, unknownBoard},
+		{"When game is lost", 3, 1, GameStateLost, emptyBoard, unknownBoard},
+		{"When game is abandoned", 4, 1, GameStateAbandoned, emptyBoard, unknownBoard},
+		{"When game is abandoned for the other player", 4, 2, GameStateAbandoned, emptyBoard, unknownBoard},
+		{"When game is running and its players turn", 1, 1, GameStateMyTurn, player1Board, player1HitBoard},
+		{"When game is running and its not the players turn", 1, 2, GameStateNotMyTurn, player2Board, player2HitBoard},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			message := ConstructGameUpdateMessage(db, tc.gameID, tc.playerID, tc.turn)
+			message := ConstructGameUpdateMessage(db, tc.gameID, tc.playerID)
 
 			if message.Status != tc.expectedStatus {
 				t.Fatalf("Expecting game status to be %d but was %d", tc.expectedStatus, message.Status)
@@ -40,6 +68,251 @@ func TestConstructGameUpdateMessage(t *testing.T) {
 			if !deepCheck(tc.expectedMyState, message.MyBoard) {
 				t.Fatalf("Expecting MyBoard") // state to be" %v was %v", tc.expectedMyState, message.MyBoard)
 			}
+
+			if !deepCheck(tc.expectedHitState, message.HitBoard) {
+				t.Fatalf("Expecting HitBoard state to be %v was %v", tc.expectedHitState, message.HitBoard)
+			}
+		})
+	}
+}
+
+func TestResolveShot(t *testing.T) {
+
+	// A destroyer with one hole in it and a cruiser that has already been sunk
+	fleet := func(cruiserSunk bool) []Ship {
+		return []Ship{
+			Ship{Size: 2, Location: []Coord{Coord{X: 1, Y: 1, Hit: true}, Coord{X: 2, Y: 1}}},
+			Ship{Size: 3, Sunk: cruiserSunk, Location: []Coord{
+				Coord{X: 5, Y: 5, Hit: true}, Coord{X: 5, Y: 6, Hit: true}, Coord{X: 5, Y: 7, Hit: cruiserSunk},
+			}},
+		}
+	}
+
+	tt := []struct {
+		name             string
+		ships            []Ship
+		target           Coord
+		expectedOutcome  MoveOutcome
+		expectedShip     int
+		expectedLocation int
+		expectedSunk     bool
+	}{
+		{"When the shot misses", fleet(false), Coord{X: 0, Y: 0}, OutcomeShipMiss, -1, -1, false},
+		{"When the shot hits a ship that stays afloat", []Ship{Ship{Size: 3, Location: []Coord{Coord{X: 0, Y: 0}, Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}}}}, Coord{X: 0, Y: 1}, OutcomeShipHit, 0, 1, false},
+		{"When the shot sinks a ship", fleet(false), Coord{X: 2, Y: 1}, OutcomeShipSunk, 0, 1, true},
+		{"When the shot sinks a ship while another is afloat", fleet(false), Coord{X: 5, Y: 7}, OutcomeShipSunk, 1, 2, true},
+		{"When the shot sinks the last ship", fleet(true), Coord{X: 2, Y: 1}, OutcomeWon, 0, 1, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			outcome, shipIndex, locationIndex := resolveShot(tc.ships, tc.target)
+
+			if outcome != tc.expectedOutcome {
+				t.Fatalf("Expecting outcome %d but was %d", tc.expectedOutcome, outcome)
+			}
+
+			if shipIndex != tc.expectedShip || locationIndex != tc.expectedLocation {
+				t.Fatalf("Expecting location %d of ship %d but was %d of ship %d", tc.expectedLocation, tc.expectedShip, locationIndex, shipIndex)
+			}
+
+			if shipIndex == -1 {
+				return
+			}
+
+			ship := tc.ships[shipIndex]
+			if !ship.Location[locationIndex].Hit {
+				t.Fatalf("Expecting the location to be marked as hit")
+			}
+
+			if ship.Sunk != tc.expectedSunk {
+				t.Fatalf("Expecting the ship sunk to be %v but was %v", tc.expectedSunk, ship.Sunk)
+			}
+		})
+	}
+}
+
+func TestVolleyOutcome(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		outcomes []MoveOutcome
+		expected MoveOutcome
+	}{
+		{"When every shot misses", []MoveOutcome{OutcomeShipMiss, OutcomeShipMiss}, OutcomeShipMiss},
+		{"When one shot hits", []MoveOutcome{OutcomeShipMiss, OutcomeShipHit}, OutcomeShipHit},
+		{"When one shot sinks a ship", []MoveOutcome{OutcomeShipHit, OutcomeShipSunk, OutcomeShipMiss}, OutcomeShipSunk},
+		{"When the last ship is sunk before the volley ends", []MoveOutcome{OutcomeWon, OutcomeShipMiss}, OutcomeWon},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var results []ShotResult
+			for _, outcome := range tc.outcomes {
+				results = append(results, ShotResult{Outcome: outcome})
+			}
+
+			if outcome := volleyOutcome(results); outcome != tc.expected {
+				t.Fatalf("Expecting outcome %d but was %d", tc.expected, outcome)
+			}
+		})
+	}
+}
+
+func TestNextPlayer(t *testing.T) {
+
+	seats := []Seat{
+		Seat{PlayerID: 1, Seat: 0, Team: 0},
+		Seat{PlayerID: 2, Seat: 1, Team: 1, Eliminated: true},
+		Seat{PlayerID: 3, Seat: 2, Team: 2},
+		Seat{PlayerID: 4, Seat: 3, Team: 3},
+	}
+
+	tt := []struct {
+		name     string
+		seats    []Seat
+		playerID int
+		expected int
+	}{
+		{"When the next seat is still in", seats, 3, 4},
+		{"When the turn wraps around the table", seats, 4, 1},
+		{"When the next seat has been eliminated", seats, 1, 3},
+		{"When every other player has been eliminated", []Seat{Seat{PlayerID: 1}, Seat{PlayerID: 2, Seat: 1, Team: 1, Eliminated: true}}, 1, 2},
+		{"When there is only one seat", []Seat{Seat{PlayerID: 1}}, 1, -1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if next := nextPlayer(tc.seats, tc.playerID); next != tc.expected {
+				t.Fatalf("Expecting player %d to be next but was %d", tc.expected, next)
+			}
+		})
+	}
+}
+
+func TestTargetFor(t *testing.T) {
+
+	seats := []Seat{
+		Seat{PlayerID: 1, Seat: 0, Team: 0},
+		Seat{PlayerID: 2, Seat: 1, Team: 1, Eliminated: true},
+		Seat{PlayerID: 3, Seat: 2, Team: 2},
+	}
+
+	tt := []struct {
+		name     string
+		target   int
+		expected int
+	}{
+		{"When no target is given", 0, 3},
+		{"When a player still in is targeted", 3, 3},
+		{"When an eliminated player is targeted", 2, -1},
+		{"When the player targets themselves", 1, -1},
+		{"When the target is not in the game", 9, -1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if target := targetFor(seats, 1, tc.target); target != tc.expected {
+				t.Fatalf("Expecting target %d but was %d", tc.expected, target)
+			}
+		})
+	}
+}
+
+func TestNextOpponent(t *testing.T) {
+
+	seats := []Seat{
+		Seat{PlayerID: 1, Seat: 0, Team: 0},
+		Seat{PlayerID: 2, Seat: 1, Team: 1},
+		Seat{PlayerID: 3, Seat: 2, Team: 0},
+		Seat{PlayerID: 4, Seat: 3, Team: 1},
+	}
+
+	tt := []struct {
+		name     string
+		seats    []Seat
+		playerID int
+		expected int
+	}{
+		{"When the next seat is on the other team", seats, 1, 2},
+		{"When the turn wraps around the table", seats, 4, 1},
+		{"When the next opponent has been eliminated", eliminateSeat(seats, 2), 1, 4},
+		{"When the other team has been eliminated", eliminateSeat(eliminateSeat(seats, 2), 4), 1, 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if next := nextOpponent(tc.seats, tc.playerID); next != tc.expected {
+				t.Fatalf("Expecting player %d to be the next opponent but was %d", tc.expected, next)
+			}
+
+			if next := nextPlayer(tc.seats, tc.playerID); next == tc.playerID {
+				t.Fatalf("Expecting the turn to move on from player %d", tc.playerID)
+			}
+		})
+	}
+}
+
+func TestTeamsLeft(t *testing.T) {
+
+	seats := []Seat{
+		Seat{PlayerID: 1, Seat: 0, Team: 0},
+		Seat{PlayerID: 2, Seat: 1, Team: 1},
+		Seat{PlayerID: 3, Seat: 2, Team: 0},
+		Seat{PlayerID: 4, Seat: 3, Team: 1},
+	}
+
+	tt := []struct {
+		name       string
+		eliminated []int
+		expected   int
+	}{
+		{"When every player is still in", nil, 2},
+		{"When one player of a team is eliminated", []int{2}, 2},
+		{"When a player of each team is eliminated", []int{1, 4}, 2},
+		{"When a whole team is eliminated", []int{2, 4}, 1},
+		{"When everyone is eliminated", []int{1, 2, 3, 4}, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			remaining := seats
+			for _, playerID := range tc.eliminated {
+				remaining = eliminateSeat(remaining, playerID)
+			}
+
+			if left := teamsLeft(remaining); left != tc.expected {
+				t.Fatalf("Expecting %d teams left but was %d", tc.expected, left)
+			}
+		})
+	}
+}
+
+func TestTargetForTeams(t *testing.T) {
+
+	seats := []Seat{
+		Seat{PlayerID: 1, Seat: 0, Team: 0},
+		Seat{PlayerID: 2, Seat: 1, Team: 1},
+		Seat{PlayerID: 3, Seat: 2, Team: 0},
+		Seat{PlayerID: 4, Seat: 3, Team: 1, Eliminated: true},
+	}
+
+	tt := []struct {
+		name     string
+		target   int
+		expected int
+	}{
+		{"When no target is given", 0, 2},
+		{"When an opponent is targeted", 2, 2},
+		{"When a teammate is targeted", 3, -1},
+		{"When an eliminated opponent is targeted", 4, -1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if target := targetFor(seats, 3, tc.target); target != tc.expected {
+				t.Fatalf("Expecting target %d but was %d", tc.expected, target)
+			}
 		})
 	}
 }
@@ -54,7 +327,8 @@ func deepCheck(expected GameBoard, player GameBoard) bool {
 		for j, _ := range expected.Coords[i] {
 			if expected.Coords[i][j].X != player.Coords[i][j].X ||
 				expected.Coords[i][j].Y != player.Coords[i][j].Y ||
-				expected.Coords[i][j].Hit != player.Coords[i][j].Hit {
+				expected.Coords[i][j].Hit != player.Coords[i][j].Hit ||
+				expected.Coords[i][j].State != player.Coords[i][j].State {
 				return false
 			}
 		}
@@ -63,6 +337,29 @@ func deepCheck(expected GameBoard, player GameBoard) bool {
 	return true
 }
 
+func TestIsSeatedInLiveGame(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		gameID   int
+		playerID int
+		expected bool
+	}{
+		{"When the player is in the running game", 1, 2, true},
+		{"When the player is not in the game", 1, 3, false},
+		{"When the game is over", 2, 1, false},
+		{"When there is no such game", 99, 1, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if seated := IsSeatedInLiveGame(db, tc.gameID, tc.playerID); seated != tc.expected {
+				t.Fatalf("Expecting player %d seated in game %d to be %v but was %v", tc.playerID, tc.gameID, tc.expected, seated)
+			}
+		})
+	}
+}
+
 func TestMain(m *testing.M) {
 	err := SetupDB()
 	if err != nil {
@@ -79,7 +376,18 @@ func TestMain(m *testing.M) {
 
 func TearDown() {
 	defer db.Close()
+	db.Exec("DROP TABLE IF EXISTS CHAT")
+	db.Exec("DROP TABLE IF EXISTS TOURNAMENT_MATCHES")
+	db.Exec("DROP TABLE IF EXISTS TOURNAMENT_PLAYERS")
+	db.Exec("DROP TABLE IF EXISTS TOURNAMENTS")
+	db.Exec("DROP TABLE IF EXISTS RELOCATIONS")
+	db.Exec("DROP TABLE IF EXISTS REVEALS")
+	db.Exec("DROP TABLE IF EXISTS MINES")
+	db.Exec("DROP TABLE IF EXISTS ABILITIES")
+	db.Exec("DROP TABLE IF EXISTS MOVES")
+	db.Exec("DROP TABLE IF EXISTS SHIP_LOCATIONS")
 	db.Exec("DROP TABLE IF EXISTS SHIPS")
+	db.Exec("DROP TABLE IF EXISTS SEATS")
 	db.Exec("DROP TABLE IF EXISTS GAMES")
 	db.Exec("DROP TABLE IF EXISTS USERS")
 }
@@ -94,11 +402,33 @@ func SetupDB() error {
 		return err
 	}
 
+	for _, table := range []string{"CHAT", "TOURNAMENT_MATCHES", "TOURNAMENT_PLAYERS", "TOURNAMENTS", "RELOCATIONS", "REVEALS", "MINES", "ABILITIES"} {
+		_, err = db.Exec("DROP TABLE IF EXISTS " + table)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = db.Exec("DROP TABLE IF EXISTS MOVES")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DROP TABLE IF EXISTS SHIP_LOCATIONS")
+	if err != nil {
+		return err
+	}
+
 	_, err = db.Exec("DROP TABLE IF EXISTS SHIPS")
 	if err != nil {
 		return err
 	}
 
+	_, err = db.Exec("DROP TABLE IF EXISTS SEATS")
+	if err != nil {
+		return err
+	}
+
 	_, err = db.Exec("DROP TABLE IF EXISTS GAMES")
 	if err != nil {
 		return err
@@ -113,7 +443,8 @@ func SetupDB() error {
 		CREATE TABLE USERS (
 			Id bigserial primary key, 
 			email text, 
-			password text
+			password text,
+			bot text UNIQUE
 		);`)
 	if err != nil {
 		return err
@@ -136,29 +467,65 @@ func SetupDB() error {
 	_, err = db.Exec(`
 		CREATE TABLE GAMES (
 			Id bigserial primary key, 
-			player1 bigint references USERS, 
-			player2 bigint references USERS, 
 			status text DEFAULT 'Started',
-			winner bigint
+			ruleSet text DEFAULT 'classic',
+			winner bigint,
+			turn bigint,
+			moves int DEFAULT 0,
+			deadline timestamptz,
+			turnStartedAt timestamptz,
+			botGame boolean DEFAULT false,
+			spectators int DEFAULT 0,
+			createdAt timestamptz DEFAULT now(),
+			moveDays int DEFAULT 0
 		)`)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO GAMES (player1, player2) VALUES (1, 2)`)
+		INSERT INTO GAMES (turn, moves) VALUES (1, 5)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO GAMES (Status, Winner) VALUES ('Completed', 1)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO GAMES (Status, Winner) VALUES ('Completed', 2)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO GAMES (Status) VALUES ('Abandoned')`)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO GAMES (player1, player2, Status, Winner) VALUES (1, 2, 'Completed', 1)`)
+		CREATE TABLE SEATS (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			playerID bigint references USERS,
+			seat smallint,
+			team smallint,
+			timeBank bigint,
+			eliminated boolean DEFAULT false,
+			UNIQUE (gameID, seat),
+			UNIQUE (gameID, playerID)
+		);`)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO GAMES (player1, player2, Status, Winner) VALUES (1, 2, 'Completed', 2)`)
+		INSERT INTO SEATS (gameID, playerID, seat, team) 
+		VALUES (1, 1, 0, 0), (1, 2, 1, 1), (2, 1, 0, 0), (2, 2, 1, 1), (3, 1, 0, 0), (3, 2, 1, 1), (4, 1, 0, 0), (4, 2, 1, 1)`)
 	if err != nil {
 		return err
 	}
@@ -169,21 +536,6 @@ func SetupDB() error {
 			gameID bigint references GAMES, 
 			playerID bigint references USERS, 
 			size int,
-			xlocation1 smallint DEFAULT -1,
-			ylocation1 smallint DEFAULT -1,
-			location1hit boolean DEFAULT false,
-			xlocation2 smallint DEFAULT -1,
-			ylocation2 smallint DEFAULT -1,
-			location2hit  boolean DEFAULT false,
-			xlocation3 smallint DEFAULT -1,
-			ylocation3 smallint DEFAULT -1,
-			location3hit  boolean DEFAULT false,
-			xlocation4 smallint DEFAULT -1,
-			ylocation4 smallint DEFAULT -1,
-			location4hit  boolean DEFAULT false,
-			xlocation5 smallint DEFAULT -1,
-			ylocation5 smallint DEFAULT -1,
-			location5hit  boolean DEFAULT false,
 			sunk boolean DEFAULT false
 		);`)
 	if err != nil {
@@ -191,41 +543,173 @@ func SetupDB() error {
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO SHIPS (gameID, playerID, size, sunk, xlocation1, ylocation1, xlocation2, ylocation2, xlocation3, ylocation3) 
-		VALUES (
-			1, 1, 3, false,
-			0, 1, 0, 2, 0, 3
-			)`)
+		CREATE TABLE SHIP_LOCATIONS (
+			Id bigserial primary key,
+			shipID bigint references SHIPS,
+			position smallint,
+			xlocation smallint,
+			ylocation smallint,
+			hit boolean DEFAULT false,
+			UNIQUE (shipID, position)
+		);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO SHIPS (gameID, playerID, size, sunk) 
+		VALUES (1, 1, 3, false), (1, 1, 2, false), (1, 2, 3, false), (1, 2, 2, true)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO SHIP_LOCATIONS (shipID, position, xlocation, ylocation, hit) 
+		VALUES 
+			(1, 0, 0, 1, true), (1, 1, 0, 2, false), (1, 2, 0, 3, false),
+			(2, 0, 1, 1, false), (2, 1, 2, 1, false),
+			(3, 0, 0, 1, false), (3, 1, 0, 2, false), (3, 2, 0, 3, false),
+			(4, 0, 1, 1, true), (4, 1, 2, 1, true)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE MOVES (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			moveNumber int,
+			shot smallint DEFAULT 0,
+			kind text DEFAULT 'shot',
+			playerID bigint references USERS,
+			target bigint references USERS,
+			xlocation smallint,
+			ylocation smallint,
+			outcome smallint,
+			createdAt timestamptz DEFAULT now(),
+			UNIQUE (gameID, moveNumber, shot)
+		);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO MOVES (gameID, moveNumber, playerID, target, xlocation, ylocation, outcome)
+		VALUES (1, 1, 1, 2, 1, 1, 4), (1, 2, 2, 1, 0, 1, 4), (1, 3, 1, 2, 2, 1, 3), (1, 4, 2, 1, 5, 5, 5), (1, 5, 1, 2, 4, 4, 5)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE ABILITIES (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			playerID bigint references USERS,
+			ability text,
+			moveNumber int,
+			xlocation smallint,
+			ylocation smallint
+		);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE MINES (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			playerID bigint references USERS,
+			xlocation smallint,
+			ylocation smallint
+		);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE REVEALS (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			playerID bigint references USERS,
+			moveNumber int,
+			xlocation smallint,
+			ylocation smallint
+		);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE RELOCATIONS (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			moveNumber int,
+			shipID bigint references SHIPS,
+			position smallint,
+			fromX smallint,
+			fromY smallint,
+			toX smallint,
+			toY smallint
+		);`)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO SHIPS (gameID, playerID, size, sunk, xlocation1, ylocation1, xlocation2, ylocation2) 
-		VALUES (
-			1, 1, 2, false,
-			1, 1, 2, 1
-			)`)
+		CREATE TABLE TOURNAMENTS (
+			Id bigserial primary key,
+			name text,
+			format text,
+			ruleSet text DEFAULT 'classic',
+			organizer bigint references USERS,
+			status text DEFAULT 'SigningUp',
+			rounds int DEFAULT 0,
+			round int DEFAULT 0,
+			startsAt timestamptz,
+			roundDeadline timestamptz,
+			winner bigint references USERS
+		);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE TOURNAMENT_PLAYERS (
+			Id bigserial primary key,
+			tournamentID bigint references TOURNAMENTS,
+			playerID bigint references USERS,
+			seed int,
+			UNIQUE (tournamentID, playerID)
+		);`)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO SHIPS (gameID, playerID, size, sunk, xlocation1, ylocation1, xlocation2, ylocation2, xlocation3, ylocation3) 
-		VALUES (
-			1, 2, 3, false,
-			0, 1, 0, 2, 0, 3
-			)`)
+		CREATE TABLE TOURNAMENT_MATCHES (
+			Id bigserial primary key,
+			tournamentID bigint references TOURNAMENTS,
+			round int,
+			slot int,
+			player1 bigint references USERS,
+			player2 bigint references USERS,
+			gameID bigint references GAMES,
+			winner bigint references USERS,
+			forfeit boolean DEFAULT false,
+			UNIQUE (tournamentID, round, slot)
+		);`)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-		INSERT INTO SHIPS (gameID, playerID, size, sunk, xlocation1, ylocation1, xlocation2, ylocation2) 
-		VALUES (
-			1, 2, 2, false,
-			1, 1, 2, 1
-			)`)
+		CREATE TABLE CHAT (
+			Id bigserial primary key,
+			gameID bigint references GAMES,
+			playerID bigint references USERS,
+			message text,
+			sentAt timestamptz DEFAULT now()
+		);`)
 	if err != nil {
 		return err
 	}
@@ -233,24 +717,24 @@ func SetupDB() error {
 	return nil
 }
 
-func constructCoords(coords []Coord) GameBoard {
+func constructBoard(defaultState CellState, coords []Coord) GameBoard {
 
 	var result GameBoard
 
-	for i := 0; i < GameBoardSize; i++ {
+	for i := 0; i < RuleSets["classic"].BoardSize; i++ {
 		var row []Coord
 
-		for j := 0; j < GameBoardSize; j++ {
+		for j := 0; j < RuleSets["classic"].BoardSize; j++ {
 
-			var locationHit bool
+			location := Coord{X: i, Y: j, State: defaultState}
 
 			for _, coord := range coords {
 				if coord.X == i && coord.Y == j {
-					locationHit = true
+					location = coord
 				}
 			}
 
-			row = append(row, Coord{i, j, locationHit})
+			row = append(row, location)
 		}
 
 		result.Coords = append(result.Coords, row)
@@ -258,3 +742,26 @@ func constructCoords(coords []Coord) GameBoard {
 
 	return result
 }
+
+func TestWaitingQueue(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		ruleSet  string
+		moveDays int
+		expected string
+	}{
+		{"When waiting for a live game with the default rules", DefaultRuleSet, 0, "WaitingQueue"},
+		{"When waiting for a live game with other rules", "salvo", 0, "WaitingQueue-salvo"},
+		{"When waiting for a correspondence game with the default rules", DefaultRuleSet, 3, "WaitingQueue-3Days"},
+		{"When waiting for a correspondence game with other rules", "salvo", 7, "WaitingQueue-salvo-7Days"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if queue := waitingQueue(tc.ruleSet, tc.moveDays); queue != tc.expected {
+				t.Fatalf("Expecting queue %s but was %s", tc.expected, queue)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// InviteTime defines how long an invite to a private game stays open
+const InviteTime = time.Minute * 15
+
+// inviteCodeLength is how many characters an invite code has
+const inviteCodeLength = 6
+
+// inviteCodeAlphabet leaves out characters that are easily mixed up when read out
+const inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+/*
+Invite is a private game waiting for players. Anyone with the Code can take a seat unless
+the Host challenged a user, then only the Challenged user can. The game is created once
+every seat of the rule set is taken. Players lists the host and everyone who has joined.
+MoveDays makes it a correspondence game with that many days for each move
+*/
+type Invite struct {
+	Code       string
+	Host       int
+	RuleSet    string
+	Challenged int
+	Players    []int
+	MoveDays   int
+	Expires    time.Time
+}
+
+// CreateInviteEventMessage is sent by the Client to create a private game. Challenge is the
+// user to challenge, leave it out to get a code anyone can join with. MoveDays asks for a
+// correspondence game, leave it out for a live game
+type CreateInviteEventMessage struct {
+	EventMessage
+	RuleSet   string
+	Challenge int
+	MoveDays  int
+}
+
+// InviteEventMessage is sent by the Client to accept or cancel an invite
+type InviteEventMessage struct {
+	EventMessage
+	Code string
+}
+
+var (
+	errInviteNotFound = errors.New("Invite has expired or does not exist")
+	errInviteJoined   = errors.New("You have already joined this game")
+	errInviteNotYours = errors.New("This invite is for someone else")
+	errInviteFull     = errors.New("Every seat in this game is taken")
+)
+
+// CreateInvite creates a private game for the player and sends them its code. A challenged
+// user is sent the invite straight away
+func CreateInvite(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message CreateInviteEventMessage, userID int) {
+
+	rules, ok := FindRuleSet(message.RuleSet)
+	if !ok {
+		PublishErrorEvent(producer, "Unknown rule set", userID, 0)
+		return
+	}
+
+	if !isValidMoveDays(message.MoveDays) {
+		PublishErrorEvent(producer, fmt.Sprintf("Correspondence games can have at most %d days for a move", maxMoveDays), userID, 0)
+		return
+	}
+
+	if message.Challenge != 0 {
+		if message.Challenge == userID {
+			PublishErrorEvent(producer, "You can not challenge yourself", userID, 0)
+			return
+		}
+
+		if rules.seats() != 2 {
+			PublishErrorEvent(producer, "Challenges are only for two player games", userID, 0)
+			return
+		}
+
+		if !IsKnownPlayer(db, message.Challenge) {
+			PublishErrorEvent(producer, "Could not find the player to challenge", userID, 0)
+			return
+		}
+	}
+
+	invite := Invite{
+		Host:       userID,
+		RuleSet:    rules.Name,
+		Challenged: message.Challenge,
+		Players:    []int{userID},
+		MoveDays:   message.MoveDays,
+		Expires:    time.Now().Add(InviteTime),
+	}
+
+	invite, err := StoreInvite(cache, invite)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, 0)
+		return
+	}
+
+	inviteMessage := EventMessage{
+		Event:   InviteCreatedEvent,
+		To:      userID,
+		Payload: invite,
+	}
+
+	inviteMessage.Send(producer)
+
+	if invite.Challenged != 0 {
+		challengeMessage := EventMessage{
+			Event:   ChallengeEvent,
+			To:      invite.Challenged,
+			Payload: invite,
+		}
+
+		challengeMessage.Send(producer)
+	}
+}
+
+// AcceptInvite takes a seat in a private game. The last player to take a seat starts the game
+func AcceptInvite(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message InviteEventMessage, userID int) {
+
+	invite, err := JoinInvite(cache, message.Code, userID)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, 0)
+		return
+	}
+
+	rules, _ := FindRuleSet(invite.RuleSet)
+
+	if !invite.isFull(rules) {
+		for _, playerID := range invite.Players {
+			inviteMessage := EventMessage{
+				Event:   InviteCreatedEvent,
+				To:      playerID,
+				Payload: invite,
+			}
+
+			inviteMessage.Send(producer)
+		}
+		return
+	}
+
+	gameID, err := createGame(db, invite.Players, invite.RuleSet, invite.MoveDays, false)
+	if err != nil {
+		for _, playerID := range invite.Players {
+			PublishErrorEvent(producer, "Could not create game", playerID, 0)
+		}
+		return
+	}
+
+	PublishGameStarted(db, producer, gameID)
+}
+
+// CancelInvite lets the host call off a private game or the challenged user turn down a challenge.
+// Everyone else in the invite is told it has been cancelled
+func CancelInvite(cache *redis.Client, producer *kafka.Producer, message InviteEventMessage, userID int) {
+
+	invite, ok := FindInvite(cache, message.Code)
+	if !ok {
+		PublishErrorEvent(producer, errInviteNotFound.Error(), userID, 0)
+		return
+	}
+
+	if userID != invite.Host && userID != invite.Challenged {
+		PublishErrorEvent(producer, "Only the host can cancel the invite", userID, 0)
+		return
+	}
+
+	// Only the first cancel tells the players
+	if !RemoveInvite(cache, message.Code) {
+		PublishErrorEvent(producer, errInviteNotFound.Error(), userID, 0)
+		return
+	}
+
+	recipients := invite.Players
+	if invite.Challenged != 0 {
+		recipients = append(recipients, invite.Challenged)
+	}
+
+	for _, playerID := range recipients {
+		if playerID == userID {
+			continue
+		}
+
+		cancelMessage := EventMessage{
+			Event:   CancelInviteEvent,
+			To:      playerID,
+			Payload: InviteEventMessage{Code: invite.Code},
+		}
+
+		cancelMessage.Send(producer)
+	}
+}
+
+// admit seats the player in the invite
+func (i Invite) admit(userID int) (Invite, error) {
+
+	if containsInt(i.Players, userID) {
+		return i, errInviteJoined
+	}
+
+	if i.Challenged != 0 && i.Challenged != userID {
+		return i, errInviteNotYours
+	}
+
+	rules, _ := FindRuleSet(i.RuleSet)
+	if i.isFull(rules) {
+		return i, errInviteFull
+	}
+
+	i.Players = append(append([]int(nil), i.Players...), userID)
+	return i, nil
+}
+
+// isFull checks every seat of the rule set has been taken
+func (i Invite) isFull(rules RuleSet) bool {
+	return len(i.Players) >= rules.seats()
+}
+
+// newInviteCode makes a random code to share with friends
+func newInviteCode() (string, error) {
+
+	code := make([]byte, inviteCodeLength)
+	size := big.NewInt(int64(len(inviteCodeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
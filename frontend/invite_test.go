@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInviteAdmit(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		invite   Invite
+		userID   int
+		expected error
+		players  int
+	}{
+		{"When a friend joins with the code", Invite{RuleSet: "classic", Host: 1, Players: []int{1}}, 2, nil, 2},
+		{"When the host joins their own game", Invite{RuleSet: "classic", Host: 1, Players: []int{1}}, 1, errInviteJoined, 1},
+		{"When the challenged user accepts", Invite{RuleSet: "classic", Host: 1, Challenged: 2, Players: []int{1}}, 2, nil, 2},
+		{"When someone else takes up a challenge", Invite{RuleSet: "classic", Host: 1, Challenged: 2, Players: []int{1}}, 3, errInviteNotYours, 1},
+		{"When the game is full", Invite{RuleSet: "classic", Host: 1, Players: []int{1, 2}}, 3, errInviteFull, 2},
+		{"When a free-for-all has seats left", Invite{RuleSet: "freeforall3", Host: 1, Players: []int{1, 2}}, 3, nil, 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			invite, err := tc.invite.admit(tc.userID)
+
+			if err != tc.expected {
+				t.Fatalf("Expecting error %v but was %v", tc.expected, err)
+			}
+
+			if len(invite.Players) != tc.players {
+				t.Fatalf("Expecting %d players but was %d", tc.players, len(invite.Players))
+			}
+		})
+	}
+}
+
+func TestNewInviteCode(t *testing.T) {
+
+	seen := make(map[string]bool)
+
+	for i := 0; i < 100; i++ {
+		code, err := newInviteCode()
+		if err != nil {
+			t.Fatalf("Expecting a code but got error %s", err.Error())
+		}
+
+		if len(code) != inviteCodeLength {
+			t.Fatalf("Expecting a code of %d characters but was %s", inviteCodeLength, code)
+		}
+
+		for _, c := range code {
+			if !strings.ContainsRune(inviteCodeAlphabet, c) {
+				t.Fatalf("Expecting the code %s to only use the invite alphabet", code)
+			}
+		}
+
+		seen[code] = true
+	}
+
+	if len(seen) < 95 {
+		t.Fatalf("Expecting codes to rarely repeat but only %d of 100 were different", len(seen))
+	}
+}
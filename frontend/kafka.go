@@ -35,7 +35,11 @@ func WatchGameUpdates() {
 			log.Printf("Message on %s: %s\n", msg.TopicPartition, string(msg.Value))
 			var message EventMessage
 			json.Unmarshal(msg.Value, &message)
-			if sock, ok := allSockets[message.To]; ok {
+			if message.To == 0 && message.GameID != 0 {
+				for _, sock := range spectatorSockets(message.GameID) {
+					sock.WriteJSON(message)
+				}
+			} else if sock, ok := allSockets[message.To]; ok {
 				sock.WriteJSON(message)
 			}
 
@@ -81,11 +85,14 @@ func ConnectProducer() *kafka.Producer {
 
 var gameUpdatesTopic = "gameUpdates"
 
-// EventMessage defines an event coming through the socket
+// EventMessage defines an event coming through the socket. A message To a user goes to their
+// socket, a message for a GameID without a user goes to every socket watching that game.
+// Messages about a game sent To a player carry its GameID so a player in several games can tell them apart
 type EventMessage struct {
 	Event   EventName
 	Payload interface{}
 	To      int
+	GameID  int
 }
 
 // Send sends a message to the Kafka Topic
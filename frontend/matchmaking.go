@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// MatchmakingFallback defines what happens when a player has waited too long for an opponent
+type MatchmakingFallback string
+
+const (
+	// FallbackBot starts a game against a bot straight away
+	FallbackBot MatchmakingFallback = "bot"
+
+	// FallbackOffer asks the player if they want to play a bot
+	FallbackOffer MatchmakingFallback = "offer"
+)
+
+// MatchmakingSettings defines how long a player waits in the WaitingQueue before a bot is used
+type MatchmakingSettings struct {
+	Timeout  time.Duration
+	Fallback MatchmakingFallback
+	Bot      BotDifficulty
+}
+
+// Matchmaking are the matchmaking settings used by the server
+var Matchmaking MatchmakingSettings
+
+// matchmakingCheckInterval is how often the servers look for players who have waited too long
+const matchmakingCheckInterval = time.Second
+
+// BotOfferEventMessage is sent to a player who has waited too long asking if they want to play a bot
+type BotOfferEventMessage struct {
+	Bot     BotDifficulty
+	RuleSet string
+}
+
+/*
+LoadMatchmakingSettings reads the matchmaking settings from the environment.
+MATCHMAKING_TIMEOUT takes a duration such as 30s, MATCHMAKING_FALLBACK is bot or offer
+and MATCHMAKING_BOT is the difficulty of the bot
+*/
+func LoadMatchmakingSettings() MatchmakingSettings {
+
+	settings := MatchmakingSettings{
+		Timeout:  durationFromEnv("MATCHMAKING_TIMEOUT"),
+		Fallback: MatchmakingFallback(os.Getenv("MATCHMAKING_FALLBACK")),
+		Bot:      BotDifficulty(os.Getenv("MATCHMAKING_BOT")),
+	}
+
+	if settings.Fallback == "" {
+		settings.Fallback = FallbackOffer
+	}
+
+	if settings.Fallback != FallbackBot && settings.Fallback != FallbackOffer {
+		panic("MATCHMAKING_FALLBACK must be bot or offer")
+	}
+
+	if settings.Bot == "" {
+		settings.Bot = BotMedium
+	}
+
+	if _, ok := botStrategies[settings.Bot]; !ok {
+		panic("MATCHMAKING_BOT must be easy, medium or hard")
+	}
+
+	return settings
+}
+
+/*
+WatchWaitingQueue takes players who have waited longer than the matchmaking timeout
+out of the WaitingQueue and either starts a bot game or offers them one. Bots only play
+live games, so players waiting for a correspondence game are never timed.
+Every server runs this, only the server which removes the player from the queue acts on it
+*/
+func WatchWaitingQueue(db *sql.DB, cache *redis.Client, producer *kafka.Producer) {
+
+	if Matchmaking.Timeout <= 0 {
+		return
+	}
+
+	for range time.Tick(matchmakingCheckInterval) {
+		for _, userID := range FindUsersWaitingSince(cache, time.Now().Add(-Matchmaking.Timeout)) {
+
+			ruleSet, _, removed := RemoveFromQueue(cache, userID)
+			if !removed {
+				continue
+			}
+
+			if Matchmaking.Fallback == FallbackBot {
+				StartBotGame(db, cache, producer, Matchmaking.Bot, ruleSet, userID)
+				continue
+			}
+
+			botOfferMessage := EventMessage{
+				Event:   BotOfferEvent,
+				To:      userID,
+				Payload: BotOfferEventMessage{Bot: Matchmaking.Bot, RuleSet: ruleSet},
+			}
+
+			botOfferMessage.Send(producer)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+/*
+ValidateFleet checks the ships placed by a player against the rules of the game.
+Every ship must be on the board, must have the shape of a ship in the fleet in any
+rotation or reflection, must not overlap another ship and the fleet must match the
+fleet of the rule set exactly. Ships cannot be placed on islands.
+Every violation found is returned, an empty list means the fleet is valid
+*/
+func ValidateFleet(rules RuleSet, ships []Ship) []string {
+
+	var violations []string
+
+	if len(ships) == 0 {
+		return append(violations, "No ships were placed")
+	}
+
+	occupied := make(map[Coord]int)
+	shapes := shipShapes(rules)
+
+	for i, ship := range ships {
+
+		shipNumber := i + 1
+
+		if ship.Size != len(ship.Location) {
+			violations = append(violations, fmt.Sprintf("Ship %d has size %d but %d locations", shipNumber, ship.Size, len(ship.Location)))
+		}
+
+		if len(ship.Location) == 0 {
+			continue
+		}
+
+		for _, location := range ship.Location {
+
+			if !rules.isOnBoard(location) {
+				violations = append(violations, fmt.Sprintf("Ship %d is outside the board at (%d, %d)", shipNumber, location.X, location.Y))
+				continue
+			}
+
+			if rules.isIsland(location) {
+				violations = append(violations, fmt.Sprintf("Ship %d is on an island at (%d, %d)", shipNumber, location.X, location.Y))
+			}
+
+			key := Coord{X: location.X, Y: location.Y}
+
+			if other, ok := occupied[key]; ok {
+				if other == shipNumber {
+					violations = append(violations, fmt.Sprintf("Ship %d uses (%d, %d) more than once", shipNumber, location.X, location.Y))
+				} else {
+					violations = append(violations, fmt.Sprintf("Ship %d overlaps ship %d at (%d, %d)", shipNumber, other, location.X, location.Y))
+				}
+				continue
+			}
+
+			occupied[key] = shipNumber
+		}
+
+		if _, ok := shapes[canonicalShape(ship.Location)]; !ok {
+			violations = append(violations, fmt.Sprintf("Ship %d does not have the shape of any ship in the fleet", shipNumber))
+		}
+	}
+
+	var expected, expectedNames []string
+	for _, spec := range rules.Fleet {
+		expected = append(expected, canonicalShape(spec.cells()))
+		expectedNames = append(expectedNames, spec.Name)
+	}
+
+	var actual, actualNames []string
+	for _, ship := range ships {
+		if len(ship.Location) == 0 {
+			continue
+		}
+
+		key := canonicalShape(ship.Location)
+		actual = append(actual, key)
+
+		// Ships without the shape of any ship in the fleet still count against the fleet
+		name, ok := shapes[key]
+		if !ok {
+			name = "unknown"
+		}
+		actualNames = append(actualNames, name)
+	}
+
+	if !sameShapes(actual, expected) {
+		violations = append(violations, fmt.Sprintf("Fleet must have %v but has %v", expectedNames, actualNames))
+	}
+
+	return violations
+}
+
+// ValidateMines checks the mines placed by a player. The rule set decides how many mines
+// are placed and every mine must be on the board away from islands and the player's ships
+func ValidateMines(rules RuleSet, ships []Ship, mines []Coord) []string {
+
+	var violations []string
+
+	if len(mines) != rules.Mines {
+		violations = append(violations, fmt.Sprintf("Must place %d mines but placed %d", rules.Mines, len(mines)))
+	}
+
+	var placed []Coord
+
+	for i, mine := range mines {
+
+		mineNumber := i + 1
+
+		if !rules.isOnBoard(mine) {
+			violations = append(violations, fmt.Sprintf("Mine %d is outside the board at (%d, %d)", mineNumber, mine.X, mine.Y))
+			continue
+		}
+
+		if rules.isIsland(mine) {
+			violations = append(violations, fmt.Sprintf("Mine %d is on an island at (%d, %d)", mineNumber, mine.X, mine.Y))
+		}
+
+		if shipIndex, _ := findShipAtLocation(ships, mine); shipIndex != -1 {
+			violations = append(violations, fmt.Sprintf("Mine %d is on ship %d at (%d, %d)", mineNumber, shipIndex+1, mine.X, mine.Y))
+		}
+
+		if containsCoord(placed, mine) {
+			violations = append(violations, fmt.Sprintf("Mine %d is placed at (%d, %d) more than once", mineNumber, mine.X, mine.Y))
+		}
+
+		placed = append(placed, mine)
+	}
+
+	return violations
+}
+
+// maxPlacementAttempts limits how many times a random layout is tried before giving up
+const maxPlacementAttempts = 1000
+
+/*
+RandomFleet places the fleet of the rule set at random positions on the board.
+Each ship is placed in a random rotation and reflection without overlapping another ship.
+Returns nil if no layout could be found
+*/
+func RandomFleet(r *rand.Rand, rules RuleSet) []Ship {
+
+	for attempt := 0; attempt < maxPlacementAttempts; attempt++ {
+		if ships := tryRandomFleet(r, rules); ships != nil {
+			return ships
+		}
+	}
+
+	return nil
+}
+
+/*
+RandomMines places the mines of the rule set at random locations away from islands and the ships
+*/
+func RandomMines(r *rand.Rand, rules RuleSet, ships []Ship) []Coord {
+
+	var open []Coord
+
+	for x := 0; x < rules.BoardSize; x++ {
+		for y := 0; y < rules.BoardSize; y++ {
+			location := Coord{X: x, Y: y}
+
+			if shipIndex, _ := findShipAtLocation(ships, location); shipIndex == -1 && !rules.isIsland(location) {
+				open = append(open, location)
+			}
+		}
+	}
+
+	r.Shuffle(len(open), func(i, j int) { open[i], open[j] = open[j], open[i] })
+
+	if len(open) < rules.Mines {
+		return nil
+	}
+
+	return open[:rules.Mines]
+}
+
+/*
+AutoPlaceShips picks a random fleet and mines for the player and sends them back to the player.
+Nothing is stored, the player places the layout with a PlaceShipsEvent once they are happy with it.
+The seed is used when it is given so that a layout can be reproduced
+*/
+func AutoPlaceShips(db *sql.DB, producer *kafka.Producer, message AutoPlaceShipsEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	if len(FindShipsForPlayer(db, gameID, userID)) > 0 {
+		PublishErrorEvent(producer, "Ships have already been placed", userID, gameID)
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	if message.Seed != nil {
+		seed = *message.Seed
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	rules := FindRuleSetForGame(db, gameID)
+	ships := RandomFleet(r, rules)
+
+	if ships == nil {
+		PublishErrorEvent(producer, "Could not place ships", userID, gameID)
+		return
+	}
+
+	mines := RandomMines(r, rules, ships)
+
+	shipsPlacedMessage := EventMessage{
+		Event:  ShipsPlacedEvent,
+		To:     userID,
+		GameID: gameID,
+		Payload: ShipsPlacedEventMessage{
+			Ships: ships,
+			Mines: mines,
+			Seed:  seed,
+		},
+	}
+
+	shipsPlacedMessage.Send(producer)
+}
+
+// tryRandomFleet makes one attempt at placing every ship. Returns nil if a ship could not be placed
+func tryRandomFleet(r *rand.Rand, rules RuleSet) []Ship {
+
+	var ships []Ship
+	occupied := make(map[Coord]bool)
+	boardSize := rules.BoardSize
+
+	for _, island := range rules.Islands {
+		occupied[Coord{X: island.X, Y: island.Y}] = true
+	}
+
+	for _, spec := range rules.Fleet {
+
+		shapes := orientations(spec.cells())
+		if len(shapes) == 0 {
+			return nil
+		}
+
+		placed := false
+
+		for attempt := 0; attempt < maxPlacementAttempts && !placed; attempt++ {
+
+			shape := shapes[r.Intn(len(shapes))]
+
+			rows, columns := shapeExtent(shape)
+			if rows > boardSize || columns > boardSize {
+				continue
+			}
+
+			x, y := r.Intn(boardSize-rows+1), r.Intn(boardSize-columns+1)
+
+			var locations []Coord
+			for _, cell := range shape {
+				locations = append(locations, Coord{X: x + cell.X, Y: y + cell.Y})
+			}
+
+			if overlaps(locations, occupied) {
+				continue
+			}
+
+			for _, location := range locations {
+				occupied[location] = true
+			}
+
+			ships = append(ships, Ship{Size: len(locations), Location: locations})
+			placed = true
+		}
+
+		if !placed {
+			return nil
+		}
+	}
+
+	return ships
+}
+
+// overlaps checks if any of the locations are already occupied
+func overlaps(locations []Coord, occupied map[Coord]bool) bool {
+	for _, location := range locations {
+		if occupied[location] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shipShapes maps the canonical shape of every ship in the fleet to the name of the ship
+func shipShapes(rules RuleSet) map[string]string {
+
+	shapes := make(map[string]string)
+
+	for _, spec := range rules.Fleet {
+		key := canonicalShape(spec.cells())
+		if _, ok := shapes[key]; !ok {
+			shapes[key] = spec.Name
+		}
+	}
+
+	return shapes
+}
+
+// canonicalShape is the same for every rotation, reflection and position of a shape
+func canonicalShape(cells []Coord) string {
+
+	var canonical string
+
+	for i, orientation := range orientations(cells) {
+		if key := shapeKey(orientation); i == 0 || key < canonical {
+			canonical = key
+		}
+	}
+
+	return canonical
+}
+
+// orientations lists every distinct rotation and reflection of the shape moved to start at (0, 0)
+func orientations(cells []Coord) [][]Coord {
+
+	var result [][]Coord
+	seen := make(map[string]bool)
+	current := cells
+
+	for reflection := 0; reflection < 2; reflection++ {
+		for rotation := 0; rotation < 4; rotation++ {
+			normalized := normalizeShape(current)
+
+			if key := shapeKey(normalized); !seen[key] {
+				seen[key] = true
+				result = append(result, normalized)
+			}
+
+			current = rotateShape(current)
+		}
+
+		current = reflectShape(current)
+	}
+
+	return result
+}
+
+// rotateShape turns the shape a quarter turn
+func rotateShape(cells []Coord) []Coord {
+
+	var result []Coord
+	for _, cell := range cells {
+		result = append(result, Coord{X: cell.Y, Y: -cell.X})
+	}
+
+	return result
+}
+
+// reflectShape mirrors the shape
+func reflectShape(cells []Coord) []Coord {
+
+	var result []Coord
+	for _, cell := range cells {
+		result = append(result, Coord{X: cell.X, Y: -cell.Y})
+	}
+
+	return result
+}
+
+// normalizeShape moves the shape so its smallest coordinates are (0, 0) and sorts the locations
+func normalizeShape(cells []Coord) []Coord {
+
+	if len(cells) == 0 {
+		return nil
+	}
+
+	minX, minY := cells[0].X, cells[0].Y
+	for _, cell := range cells {
+		if cell.X < minX {
+			minX = cell.X
+		}
+		if cell.Y < minY {
+			minY = cell.Y
+		}
+	}
+
+	var result []Coord
+	for _, cell := range cells {
+		result = append(result, Coord{X: cell.X - minX, Y: cell.Y - minY})
+	}
+
+	sortCoords(result)
+
+	return result
+}
+
+// shapeKey writes the locations of a normalized shape as a string so shapes can be compared
+func shapeKey(cells []Coord) string {
+
+	var key strings.Builder
+	for _, cell := range cells {
+		fmt.Fprintf(&key, "%d,%d;", cell.X, cell.Y)
+	}
+
+	return key.String()
+}
+
+// shapeExtent is the number of rows and columns a normalized shape covers
+func shapeExtent(cells []Coord) (int, int) {
+
+	rows, columns := 0, 0
+	for _, cell := range cells {
+		if cell.X+1 > rows {
+			rows = cell.X + 1
+		}
+		if cell.Y+1 > columns {
+			columns = cell.Y + 1
+		}
+	}
+
+	return rows, columns
+}
+
+// sameShapes checks if both lists contain the same shapes ignoring order
+func sameShapes(actual []string, expected []string) bool {
+
+	if len(actual) != len(expected) {
+		return false
+	}
+
+	a := append([]string(nil), actual...)
+	e := append([]string(nil), expected...)
+	sort.Strings(a)
+	sort.Strings(e)
+
+	for i := range a {
+		if a[i] != e[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var testRules = RuleSet{
+	Name:      "test",
+	BoardSize: 9,
+	Fleet:     []ShipSpec{{Name: "Cruiser", Size: 3}, {Name: "Destroyer", Size: 2}},
+}
+
+func TestValidateFleet(t *testing.T) {
+
+	cruiser := Ship{Size: 3, Location: []Coord{Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}, Coord{X: 0, Y: 3}}}
+	destroyer := Ship{Size: 2, Location: []Coord{Coord{X: 1, Y: 1}, Coord{X: 2, Y: 1}}}
+
+	tt := []struct {
+		name               string
+		ships              []Ship
+		expectedViolations int
+	}{
+		{"When the fleet is valid", []Ship{cruiser, destroyer}, 0},
+		{"When no ships are placed", []Ship{}, 1},
+		{"When a ship is off the board", []Ship{cruiser, Ship{Size: 2, Location: []Coord{Coord{X: testRules.BoardSize - 1, Y: 0}, Coord{X: testRules.BoardSize, Y: 0}}}}, 1},
+		{"When ships overlap", []Ship{cruiser, Ship{Size: 2, Location: []Coord{Coord{X: 0, Y: 1}, Coord{X: 1, Y: 1}}}}, 1},
+		{"When a ship is bent", []Ship{Ship{Size: 3, Location: []Coord{Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}, Coord{X: 1, Y: 2}}}, destroyer}, 2},
+		{"When a ship has a gap", []Ship{Ship{Size: 3, Location: []Coord{Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}, Coord{X: 0, Y: 4}}}, destroyer}, 2},
+		{"When the size does not match the locations", []Ship{Ship{Size: 3, Location: []Coord{Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}}}, destroyer}, 2},
+		{"When a ship has no locations", []Ship{cruiser, Ship{Size: 2}}, 2},
+		{"When the fleet is incomplete", []Ship{cruiser}, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := ValidateFleet(testRules, tc.ships)
+
+			if len(violations) != tc.expectedViolations {
+				t.Fatalf("Expecting %d violations but got %d %v", tc.expectedViolations, len(violations), violations)
+			}
+		})
+	}
+}
+
+func TestValidateShapedFleet(t *testing.T) {
+
+	rules := RuleSet{
+		Name:      "test shapes",
+		BoardSize: 9,
+		Fleet: []ShipSpec{
+			{Name: "L", Size: 4, Shape: []Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}}},
+			{Name: "Destroyer", Size: 2},
+		},
+	}
+
+	destroyer := Ship{Size: 2, Location: []Coord{Coord{X: 8, Y: 0}, Coord{X: 8, Y: 1}}}
+
+	tt := []struct {
+		name               string
+		hull               []Coord
+		expectedViolations int
+	}{
+		{"When the hull is placed as defined", []Coord{Coord{X: 0, Y: 0}, Coord{X: 1, Y: 0}, Coord{X: 2, Y: 0}, Coord{X: 2, Y: 1}}, 0},
+		{"When the hull is rotated", []Coord{Coord{X: 3, Y: 5}, Coord{X: 3, Y: 4}, Coord{X: 3, Y: 3}, Coord{X: 4, Y: 3}}, 0},
+		{"When the hull is reflected", []Coord{Coord{X: 0, Y: 1}, Coord{X: 1, Y: 1}, Coord{X: 2, Y: 1}, Coord{X: 2, Y: 0}}, 0},
+		{"When the hull is a T", []Coord{Coord{X: 0, Y: 0}, Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}, Coord{X: 1, Y: 1}}, 2},
+		{"When the hull is straight", []Coord{Coord{X: 0, Y: 0}, Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}, Coord{X: 0, Y: 3}}, 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ships := []Ship{Ship{Size: 4, Location: tc.hull}, destroyer}
+			violations := ValidateFleet(rules, ships)
+
+			if len(violations) != tc.expectedViolations {
+				t.Fatalf("Expecting %d violations but got %d %v", tc.expectedViolations, len(violations), violations)
+			}
+		})
+	}
+}
+
+func TestRandomFleet(t *testing.T) {
+
+	for _, rules := range []RuleSet{testRules, RuleSets["shapes"], RuleSets["archipelago"]} {
+		for seed := int64(0); seed < 100; seed++ {
+			r := rand.New(rand.NewSource(seed))
+			ships := RandomFleet(r, rules)
+
+			if violations := ValidateFleet(rules, ships); len(violations) > 0 {
+				t.Fatalf("Expecting seed %d to place a valid %s fleet but got %v", seed, rules.Name, violations)
+			}
+
+			if violations := ValidateMines(rules, ships, RandomMines(r, rules, ships)); len(violations) > 0 {
+				t.Fatalf("Expecting seed %d to place valid %s mines but got %v", seed, rules.Name, violations)
+			}
+
+			again := RandomFleet(rand.New(rand.NewSource(seed)), rules)
+
+			if !reflect.DeepEqual(ships, again) {
+				t.Fatalf("Expecting seed %d to place the same %s fleet every time", seed, rules.Name)
+			}
+		}
+	}
+}
+
+func TestValidateMines(t *testing.T) {
+
+	rules := testRules
+	rules.Islands = []Coord{{X: 5, Y: 5}}
+	rules.Mines = 2
+
+	ships := []Ship{
+		Ship{Size: 3, Location: []Coord{Coord{X: 0, Y: 1}, Coord{X: 0, Y: 2}, Coord{X: 0, Y: 3}}},
+		Ship{Size: 2, Location: []Coord{Coord{X: 1, Y: 1}, Coord{X: 2, Y: 1}}},
+	}
+
+	tt := []struct {
+		name               string
+		ships              []Ship
+		mines              []Coord
+		expectedViolations int
+	}{
+		{"When the mines are valid", ships, []Coord{Coord{X: 4, Y: 4}, Coord{X: 6, Y: 6}}, 0},
+		{"When too few mines are placed", ships, []Coord{Coord{X: 4, Y: 4}}, 1},
+		{"When a mine is on an island", ships, []Coord{Coord{X: 4, Y: 4}, Coord{X: 5, Y: 5}}, 1},
+		{"When a mine is on a ship", ships, []Coord{Coord{X: 4, Y: 4}, Coord{X: 0, Y: 2}}, 1},
+		{"When a mine is off the board", ships, []Coord{Coord{X: 4, Y: 4}, Coord{X: 9, Y: 0}}, 1},
+		{"When both mines are in the same place", ships, []Coord{Coord{X: 4, Y: 4}, Coord{X: 4, Y: 4}}, 1},
+		{"When a ship is on an island", []Ship{ships[0], Ship{Size: 2, Location: []Coord{Coord{X: 5, Y: 5}, Coord{X: 5, Y: 6}}}}, []Coord{Coord{X: 4, Y: 4}, Coord{X: 6, Y: 6}}, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := append(ValidateFleet(rules, tc.ships), ValidateMines(rules, tc.ships, tc.mines)...)
+
+			if len(violations) != tc.expectedViolations {
+				t.Fatalf("Expecting %d violations but got %d %v", tc.expectedViolations, len(violations), violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,836 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+/*
+GameRecord is a whole game written down so it can be shared and replayed away from the
+server. Fleets are as they were placed and Moves only keep what the notation records.
+A Winner of 0 is a game that ended without one.
+
+The notation has a header of tags, then the fleets, then one line per move:
+
+	[Game "12"]
+	[Date "2020.01.31"]
+	[RuleSet "classic"]
+	[Players "1 2"]
+	[Result "1"]
+
+	Fleet 1: A1 A2 A3, C5 D5
+	Mines 1: J10
+	Fleet 2: B2 B3 B4, F1 G1
+
+	1. 1>2 C5 C6x C7+
+	2. 2 moves B2 B3 B4 > C2 C3 C4
+
+Locations are a row letter and a column number. A shot is a miss unless marked x for a hit,
++ for a sunk ship, # for the shot that won the game, * for a mine or - for a lost game.
+The Game tag is left out for records that did not come from this server and a Date of
+????.??.?? or Result of * means it is not known
+*/
+type GameRecord struct {
+	GameID  int
+	Date    time.Time
+	RuleSet string
+	Players []int
+	Winner  int
+	Fleets  []FleetRecord
+	Moves   []Move
+}
+
+// FleetRecord is where a player placed their ships and mines. Each ship is the list of its locations
+type FleetRecord struct {
+	PlayerID int
+	Ships    [][]Coord
+	Mines    []Coord
+}
+
+// GameRecordEventMessage is sent by the Client to ask for the record of a completed game
+// and by the Server with the Record
+type GameRecordEventMessage struct {
+	EventMessage
+	Record string
+}
+
+// ImportRecordEventMessage is sent by the Client to replay a record up to the move number
+type ImportRecordEventMessage struct {
+	EventMessage
+	Record     string
+	MoveNumber int
+}
+
+// recordDateLayout is how dates are written in the header
+const recordDateLayout = "2006.01.02"
+
+// unknownRecordDate is written when the date of the game is not known
+const unknownRecordDate = "????.??.??"
+
+// recordTags are the tags of the header in the order they are written
+var recordTags = []string{"Game", "Date", "RuleSet", "Players", "Result"}
+
+// shotMark is the mark written after a shot with the outcome
+type shotMark struct {
+	Outcome MoveOutcome
+	Mark    string
+}
+
+// shotMarks are the marks written after a shot for each outcome, longest first so a mark is
+// never mistaken for the end of a longer one. A miss has no mark
+var shotMarks = []shotMark{
+	{OutcomeShipHit, "x"},
+	{OutcomeShipSunk, "+"},
+	{OutcomeWon, "#"},
+	{OutcomeMineHit, "*"},
+	{OutcomeLost, "-"},
+}
+
+// markFor finds the mark written after a shot with the outcome
+func markFor(outcome MoveOutcome) string {
+
+	for _, m := range shotMarks {
+		if m.Outcome == outcome {
+			return m.Mark
+		}
+	}
+
+	return ""
+}
+
+// ExportGameRecord writes down a game that is over
+func ExportGameRecord(db *sql.DB, gameID int) (GameRecord, error) {
+
+	status, winner, date, ok := FindGameForRecord(db, gameID)
+	if !ok {
+		return GameRecord{}, errors.New("Could not find game")
+	}
+
+	if status == "Started" {
+		return GameRecord{}, errors.New("Only games that are over can be exported")
+	}
+
+	moves := FindMovesForGame(db, gameID)
+
+	record := GameRecord{
+		GameID:  gameID,
+		Date:    date,
+		RuleSet: FindRuleSetForGame(db, gameID).Name,
+		Winner:  winner,
+		Moves:   notationMoves(moves),
+	}
+
+	for _, seat := range FindSeatsForGame(db, gameID) {
+		record.Players = append(record.Players, seat.PlayerID)
+
+		fleet := FleetRecord{PlayerID: seat.PlayerID}
+
+		// The record has the fleet as it was placed
+		for _, ship := range undoRelocations(resetFleet(FindShipsForPlayer(db, gameID, seat.PlayerID)), moves) {
+			fleet.Ships = append(fleet.Ships, ship.Location)
+		}
+
+		fleet.Mines = FindMinesForPlayer(db, gameID, seat.PlayerID)
+		record.Fleets = append(record.Fleets, fleet)
+	}
+
+	return record, nil
+}
+
+// SendGameRecord sends the player the record of a game that is over
+func SendGameRecord(db *sql.DB, producer *kafka.Producer, message GameRecordEventMessage, userID int) {
+
+	record, err := ExportGameRecord(db, message.GameID)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, message.GameID)
+		return
+	}
+
+	recordMessage := EventMessage{
+		Event:   GameRecordEvent,
+		To:      userID,
+		GameID:  message.GameID,
+		Payload: GameRecordEventMessage{EventMessage: EventMessage{GameID: message.GameID}, Record: WriteGameRecord(record)},
+	}
+
+	recordMessage.Send(producer)
+}
+
+// ImportGameRecord reads a record and sends the player the boards after the move number
+func ImportGameRecord(producer *kafka.Producer, message ImportRecordEventMessage, userID int) {
+
+	replay, err := replayRecord(message.Record, message.MoveNumber)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, 0)
+		return
+	}
+
+	replayMessage := EventMessage{
+		Event:   ReplayResultEvent,
+		To:      userID,
+		GameID:  replay.GameID,
+		Payload: replay,
+	}
+
+	replayMessage.Send(producer)
+}
+
+// replayRecord reads the record and replays it up to the move number
+func replayRecord(text string, moveNumber int) (GameReplay, error) {
+
+	record, err := ParseGameRecord(text)
+	if err != nil {
+		return GameReplay{}, err
+	}
+
+	return record.replay(moveNumber)
+}
+
+// replay rebuilds the boards of every player after the move number
+func (r GameRecord) replay(moveNumber int) (GameReplay, error) {
+
+	rules, _ := FindRuleSet(r.RuleSet)
+	fleets := make(map[int][]Ship)
+	mines := make(map[int][]Coord)
+
+	for _, fleet := range r.Fleets {
+		for i, locations := range fleet.Ships {
+			ship := Ship{ID: i + 1, Size: len(locations)}
+			for _, location := range locations {
+				ship.Location = append(ship.Location, Coord{X: location.X, Y: location.Y})
+			}
+
+			fleets[fleet.PlayerID] = append(fleets[fleet.PlayerID], ship)
+		}
+
+		mines[fleet.PlayerID] = fleet.Mines
+	}
+
+	// The replay works back from where the ships ended up, so play the relocations
+	// forward first and work out which ship each one moved
+	moves := append([]Move(nil), r.Moves...)
+
+	for i, move := range moves {
+		if move.Kind != MoveRelocation {
+			continue
+		}
+
+		shipID := shipAt(fleets[move.PlayerID], move.From)
+		if shipID == 0 {
+			return GameReplay{}, fmt.Errorf("Move %d moves a ship that is not there", move.Number)
+		}
+
+		moves[i].ShipID = shipID
+		moveShip(fleets[move.PlayerID], shipID, move.To)
+	}
+
+	replay := replayMoves(rules.BoardSize, r.Players, fleets, moves, moveNumber)
+	replay.GameID = r.GameID
+
+	for _, board := range replay.Boards {
+		markMines(board.Board, mines[board.PlayerID])
+		markIslands(board.Board, rules.Islands)
+	}
+
+	return replay, nil
+}
+
+// shipAt finds the ship at exactly the locations. Returns 0 if there is none
+func shipAt(ships []Ship, locations []Coord) int {
+
+	for _, ship := range ships {
+		if len(ship.Location) != len(locations) {
+			continue
+		}
+
+		same := true
+		for i, location := range locations {
+			if ship.Location[i].X != location.X || ship.Location[i].Y != location.Y {
+				same = false
+			}
+		}
+
+		if same {
+			return ship.ID
+		}
+	}
+
+	return 0
+}
+
+// notationMoves keeps only what the notation records of each move
+func notationMoves(moves []Move) []Move {
+
+	var result []Move
+	for _, move := range moves {
+		if move.Kind == MoveRelocation {
+			result = append(result, Move{
+				Number:   move.Number,
+				Kind:     MoveRelocation,
+				PlayerID: move.PlayerID,
+				From:     plainCoords(move.From),
+				To:       plainCoords(move.To),
+			})
+			continue
+		}
+
+		result = append(result, Move{
+			Number:   move.Number,
+			Shot:     move.Shot,
+			Kind:     MoveShot,
+			PlayerID: move.PlayerID,
+			Target:   move.Target,
+			Location: Coord{X: move.Location.X, Y: move.Location.Y},
+			Outcome:  move.Outcome,
+		})
+	}
+
+	return result
+}
+
+// plainCoords copies the locations without their state
+func plainCoords(locations []Coord) []Coord {
+
+	var result []Coord
+	for _, location := range locations {
+		result = append(result, Coord{X: location.X, Y: location.Y})
+	}
+
+	return result
+}
+
+// WriteGameRecord writes the record in the notation
+func WriteGameRecord(r GameRecord) string {
+
+	var b strings.Builder
+
+	if r.GameID != 0 {
+		writeTag(&b, "Game", strconv.Itoa(r.GameID))
+	}
+
+	date := unknownRecordDate
+	if !r.Date.IsZero() {
+		date = r.Date.Format(recordDateLayout)
+	}
+
+	writeTag(&b, "Date", date)
+	writeTag(&b, "RuleSet", r.RuleSet)
+
+	var players []string
+	for _, playerID := range r.Players {
+		players = append(players, strconv.Itoa(playerID))
+	}
+
+	writeTag(&b, "Players", strings.Join(players, " "))
+
+	result := "*"
+	if r.Winner != 0 {
+		result = strconv.Itoa(r.Winner)
+	}
+
+	writeTag(&b, "Result", result)
+	b.WriteString("\n")
+
+	for _, fleet := range r.Fleets {
+		var ships []string
+		for _, ship := range fleet.Ships {
+			ships = append(ships, coordNames(ship))
+		}
+
+		writeLine(&b, fmt.Sprintf("Fleet %d:", fleet.PlayerID), strings.Join(ships, ", "))
+
+		if len(fleet.Mines) > 0 {
+			writeLine(&b, fmt.Sprintf("Mines %d:", fleet.PlayerID), coordNames(fleet.Mines))
+		}
+	}
+
+	if len(r.Moves) > 0 {
+		b.WriteString("\n")
+	}
+
+	for i := 0; i < len(r.Moves); {
+		move := r.Moves[i]
+
+		if move.Kind == MoveRelocation {
+			fmt.Fprintf(&b, "%d. %d moves %s > %s\n", move.Number, move.PlayerID, coordNames(move.From), coordNames(move.To))
+			i++
+			continue
+		}
+
+		// Every shot of a volley goes on the same line
+		fmt.Fprintf(&b, "%d. %d>%d", move.Number, move.PlayerID, move.Target)
+		for ; i < len(r.Moves) && r.Moves[i].Kind != MoveRelocation && r.Moves[i].Number == move.Number; i++ {
+			b.WriteString(" " + coordName(r.Moves[i].Location) + markFor(r.Moves[i].Outcome))
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func writeTag(b *strings.Builder, name string, value string) {
+	fmt.Fprintf(b, "[%s \"%s\"]\n", name, value)
+}
+
+// writeLine writes the label and the value, leaving out the space when there is no value
+func writeLine(b *strings.Builder, label string, value string) {
+
+	if value == "" {
+		b.WriteString(label + "\n")
+		return
+	}
+
+	b.WriteString(label + " " + value + "\n")
+}
+
+// ParseGameRecord reads a record written in the notation
+func ParseGameRecord(text string) (GameRecord, error) {
+
+	var record GameRecord
+	var rules RuleSet
+	tags := make(map[string]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNumber := 0
+	section := "header"
+
+	fail := func(format string, args ...interface{}) (GameRecord, error) {
+		return GameRecord{}, fmt.Errorf("Line %d: "+format, append([]interface{}{lineNumber}, args...)...)
+	}
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if section != "header" {
+				return fail("tags must come before the fleets and moves")
+			}
+
+			name, value, err := parseTag(line)
+			if err != nil {
+				return fail(err.Error())
+			}
+
+			if _, seen := tags[name]; seen {
+				return fail("%s tag is given twice", name)
+			}
+
+			tags[name] = value
+			continue
+		}
+
+		if section == "header" {
+			var err error
+			record, rules, err = parseHeader(tags)
+			if err != nil {
+				return fail(err.Error())
+			}
+
+			section = "fleets"
+		}
+
+		if strings.HasPrefix(line, "Fleet ") || strings.HasPrefix(line, "Mines ") {
+			if section != "fleets" {
+				return fail("fleets must come before the moves")
+			}
+
+			err := parseFleetLine(&record, rules, line)
+			if err != nil {
+				return fail(err.Error())
+			}
+			continue
+		}
+
+		section = "moves"
+
+		moves, err := parseMoveLine(record, rules, line)
+		if err != nil {
+			return fail(err.Error())
+		}
+
+		if len(record.Moves) > 0 && moves[0].Number <= record.Moves[len(record.Moves)-1].Number {
+			return fail("moves must be in order")
+		}
+
+		record.Moves = append(record.Moves, moves...)
+	}
+
+	if section == "header" {
+		var err error
+		record, _, err = parseHeader(tags)
+		if err != nil {
+			return fail(err.Error())
+		}
+	}
+
+	return record, nil
+}
+
+// parseTag reads a [Name "Value"] tag
+func parseTag(line string) (string, string, error) {
+
+	if !strings.HasSuffix(line, "\"]") {
+		return "", "", errors.New("tag must look like [Name \"Value\"]")
+	}
+
+	parts := strings.SplitN(line[1:len(line)-2], " \"", 2)
+	if len(parts) != 2 || strings.Contains(parts[1], "\"") {
+		return "", "", errors.New("tag must look like [Name \"Value\"]")
+	}
+
+	for _, tag := range recordTags {
+		if tag == parts[0] {
+			return parts[0], parts[1], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unknown tag %s", parts[0])
+}
+
+// parseHeader reads the tags into a record and finds its rule set
+func parseHeader(tags map[string]string) (GameRecord, RuleSet, error) {
+
+	var record GameRecord
+
+	for _, tag := range recordTags[1:] {
+		if _, ok := tags[tag]; !ok {
+			return record, RuleSet{}, fmt.Errorf("%s tag is missing", tag)
+		}
+	}
+
+	if game, ok := tags["Game"]; ok {
+		gameID, err := strconv.Atoi(game)
+		if err != nil || gameID <= 0 {
+			return record, RuleSet{}, fmt.Errorf("invalid game %s", game)
+		}
+
+		record.GameID = gameID
+	}
+
+	if tags["Date"] != unknownRecordDate {
+		date, err := time.Parse(recordDateLayout, tags["Date"])
+		if err != nil {
+			return record, RuleSet{}, fmt.Errorf("invalid date %s", tags["Date"])
+		}
+
+		record.Date = date
+	}
+
+	rules, ok := RuleSets[tags["RuleSet"]]
+	if !ok {
+		return record, RuleSet{}, fmt.Errorf("unknown rule set %s", tags["RuleSet"])
+	}
+
+	record.RuleSet = rules.Name
+
+	for _, player := range strings.Fields(tags["Players"]) {
+		playerID, err := strconv.Atoi(player)
+		if err != nil || playerID <= 0 || containsInt(record.Players, playerID) {
+			return record, RuleSet{}, fmt.Errorf("invalid player %s", player)
+		}
+
+		record.Players = append(record.Players, playerID)
+	}
+
+	if len(record.Players) < 2 {
+		return record, RuleSet{}, errors.New("a game needs at least two players")
+	}
+
+	if tags["Result"] != "*" {
+		winner, err := strconv.Atoi(tags["Result"])
+		if err != nil || !containsInt(record.Players, winner) {
+			return record, RuleSet{}, fmt.Errorf("invalid result %s", tags["Result"])
+		}
+
+		record.Winner = winner
+	}
+
+	return record, rules, nil
+}
+
+// parseFleetLine reads the ships or mines of a player
+func parseFleetLine(record *GameRecord, rules RuleSet, line string) error {
+
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return errors.New("fleet must look like Fleet 1: A1 A2, C3 C4")
+	}
+
+	label := strings.Fields(parts[0])
+	if len(label) != 2 {
+		return errors.New("fleet must look like Fleet 1: A1 A2, C3 C4")
+	}
+
+	playerID, err := strconv.Atoi(label[1])
+	if err != nil || !containsInt(record.Players, playerID) {
+		return fmt.Errorf("%s is not a player in the game", label[1])
+	}
+
+	var fleet *FleetRecord
+	for i := range record.Fleets {
+		if record.Fleets[i].PlayerID == playerID {
+			fleet = &record.Fleets[i]
+		}
+	}
+
+	if label[0] == "Mines" {
+		if fleet == nil || fleet.Mines != nil {
+			return fmt.Errorf("mines of player %d must follow their fleet once", playerID)
+		}
+
+		fleet.Mines, err = parseCoords(rules, parts[1])
+		return err
+	}
+
+	if fleet != nil {
+		return fmt.Errorf("fleet of player %d is given twice", playerID)
+	}
+
+	record.Fleets = append(record.Fleets, FleetRecord{PlayerID: playerID})
+	fleet = &record.Fleets[len(record.Fleets)-1]
+
+	if strings.TrimSpace(parts[1]) == "" {
+		return nil
+	}
+
+	for _, ship := range strings.Split(parts[1], ",") {
+		locations, err := parseCoords(rules, ship)
+		if err != nil {
+			return err
+		}
+
+		if len(locations) == 0 {
+			return errors.New("ship has no locations")
+		}
+
+		fleet.Ships = append(fleet.Ships, locations)
+	}
+
+	return nil
+}
+
+// parseMoveLine reads the shots of a volley or a relocation
+func parseMoveLine(record GameRecord, rules RuleSet, line string) ([]Move, error) {
+
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasSuffix(fields[0], ".") {
+		return nil, errors.New("move must start with its number, like 1.")
+	}
+
+	number, err := strconv.Atoi(strings.TrimSuffix(fields[0], "."))
+	if err != nil || number <= 0 {
+		return nil, fmt.Errorf("invalid move number %s", fields[0])
+	}
+
+	if fields[2] == "moves" {
+		playerID, err := recordPlayer(record, fields[1])
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(strings.Join(fields[3:], " "), " > ")
+		if len(parts) != 2 {
+			return nil, errors.New("relocation must look like 1. 1 moves A1 A2 > B1 B2")
+		}
+
+		from, err := parseCoords(rules, parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := parseCoords(rules, parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(from) == 0 || len(from) != len(to) {
+			return nil, errors.New("ship must keep its size when it moves")
+		}
+
+		return []Move{{Number: number, Kind: MoveRelocation, PlayerID: playerID, From: from, To: to}}, nil
+	}
+
+	players := strings.Split(fields[1], ">")
+	if len(players) != 2 {
+		return nil, errors.New("shots must look like 1. 1>2 A1 B2x")
+	}
+
+	playerID, err := recordPlayer(record, players[0])
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := recordPlayer(record, players[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []Move
+	for shot, field := range fields[2:] {
+		location, outcome, err := parseShot(rules, field)
+		if err != nil {
+			return nil, err
+		}
+
+		moves = append(moves, Move{
+			Number:   number,
+			Shot:     shot,
+			Kind:     MoveShot,
+			PlayerID: playerID,
+			Target:   target,
+			Location: location,
+			Outcome:  outcome,
+		})
+	}
+
+	return moves, nil
+}
+
+// recordPlayer checks the player is in the game
+func recordPlayer(record GameRecord, player string) (int, error) {
+
+	playerID, err := strconv.Atoi(player)
+	if err != nil || !containsInt(record.Players, playerID) {
+		return 0, fmt.Errorf("%s is not a player in the game", player)
+	}
+
+	return playerID, nil
+}
+
+// parseShot reads a location and the mark of its outcome
+func parseShot(rules RuleSet, field string) (Coord, MoveOutcome, error) {
+
+	// Only one mark is stripped, anything left over is not a location
+	outcome := OutcomeShipMiss
+	for _, m := range shotMarks {
+		if strings.HasSuffix(field, m.Mark) {
+			outcome = m.Outcome
+			field = strings.TrimSuffix(field, m.Mark)
+			break
+		}
+	}
+
+	location, err := parseCoord(rules, field)
+	return location, outcome, err
+}
+
+// parseCoords reads a list of locations separated by spaces
+func parseCoords(rules RuleSet, text string) ([]Coord, error) {
+
+	var locations []Coord
+	for _, field := range strings.Fields(text) {
+		location, err := parseCoord(rules, field)
+		if err != nil {
+			return nil, err
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
+
+// parseCoord reads a location such as C7 and checks it is on the board
+func parseCoord(rules RuleSet, name string) (Coord, error) {
+
+	if len(name) < 2 || name[0] < 'A' || name[0] > 'Z' {
+		return Coord{}, fmt.Errorf("invalid location %s", name)
+	}
+
+	column, err := strconv.Atoi(name[1:])
+	if err != nil || name[1] == '0' || name[1] == '+' {
+		return Coord{}, fmt.Errorf("invalid location %s", name)
+	}
+
+	location := Coord{X: int(name[0] - 'A'), Y: column - 1}
+	if !rules.isOnBoard(location) {
+		return Coord{}, fmt.Errorf("location %s is off the board", name)
+	}
+
+	return location, nil
+}
+
+// coordName writes the location with a row letter and a column number, like C7
+func coordName(location Coord) string {
+	return string(rune('A'+location.X)) + strconv.Itoa(location.Y+1)
+}
+
+// coordNames writes the locations separated by spaces
+func coordNames(locations []Coord) string {
+
+	var names []string
+	for _, location := range locations {
+		names = append(names, coordName(location))
+	}
+
+	return strings.Join(names, " ")
+}
+
+/*
+gameRecordRoute exports the record of a game that is over on a GET with its id, or imports a
+record sent in the body of a POST and serves the replay after the move number asked for
+*/
+func gameRecordRoute(db *sql.DB, cache *redis.Client) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if getUserID(cache, r) == -1 {
+			http.Error(w, "Not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == "POST" {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			moveNumber, err := strconv.Atoi(r.URL.Query().Get("move"))
+			if err != nil {
+				moveNumber = 0
+			}
+
+			replay, err := replayRecord(string(body), moveNumber)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(replay)
+			return
+		}
+
+		gameID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid game id", http.StatusBadRequest)
+			return
+		}
+
+		record, err := ExportGameRecord(db, gameID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(WriteGameRecord(record)))
+	}
+}
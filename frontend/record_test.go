@@ -0,0 +1,179 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const classicRecord = `[Game "12"]
+[Date "2020.01.31"]
+[RuleSet "classic"]
+[Players "1 2"]
+[Result "1"]
+
+Fleet 1: A1 A2 A3 A4 A5, C1 C2 C3 C4, E1 E2 E3, G1 G2 G3, I1 I2
+Mines 1: J10
+Fleet 2: A1 B1 C1 D1 E1, A3 B3 C3 D3, A5 B5 C5, A7 B7 C7, A9 B9
+
+1. 1>2 A1x B1x C1 J10
+2. 2>1 A1x
+3. 2 moves A9 B9 > A10 B10
+4. 1>2 D1x E1+ A10#
+`
+
+func TestGameRecordRoundTrip(t *testing.T) {
+
+	tt := []struct {
+		name string
+		text string
+	}{
+		{"When the record is a whole game", classicRecord},
+		{"When the record has no game, date or result", "[Date \"????.??.??\"]\n[RuleSet \"salvo\"]\n[Players \"3 4\"]\n[Result \"*\"]\n\nFleet 3:\nFleet 4: B2 B3\n"},
+		{"When the record only has a header", "[Date \"2021.12.01\"]\n[RuleSet \"classic\"]\n[Players \"1 2\"]\n[Result \"*\"]\n\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := ParseGameRecord(tc.text)
+			if err != nil {
+				t.Fatalf("Expecting the record to parse but got %v", err)
+			}
+
+			if text := WriteGameRecord(record); text != tc.text {
+				t.Fatalf("Expecting the record to be written back as\n%s\nbut was\n%s", tc.text, text)
+			}
+
+			again, err := ParseGameRecord(WriteGameRecord(record))
+			if err != nil || !reflect.DeepEqual(again, record) {
+				t.Fatalf("Expecting %+v to parse back the same but was %+v %v", record, again, err)
+			}
+		})
+	}
+}
+
+func TestParseGameRecord(t *testing.T) {
+
+	record, err := ParseGameRecord(classicRecord)
+	if err != nil {
+		t.Fatalf("Expecting the record to parse but got %v", err)
+	}
+
+	if record.GameID != 12 || record.Winner != 1 || !record.Date.Equal(time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expecting game 12 on 2020.01.31 won by 1 but was %+v", record)
+	}
+
+	if len(record.Moves) != 9 {
+		t.Fatalf("Expecting 9 moves but was %d", len(record.Moves))
+	}
+
+	expected := Move{Number: 4, Shot: 1, Kind: MoveShot, PlayerID: 1, Target: 2, Location: Coord{X: 4, Y: 0}, Outcome: OutcomeShipSunk}
+	if !reflect.DeepEqual(record.Moves[7], expected) {
+		t.Fatalf("Expecting move %+v but was %+v", expected, record.Moves[7])
+	}
+
+	relocation := record.Moves[5]
+	if relocation.Kind != MoveRelocation || !reflect.DeepEqual(relocation.To, []Coord{{X: 0, Y: 9}, {X: 1, Y: 9}}) {
+		t.Fatalf("Expecting a relocation to A10 B10 but was %+v", relocation)
+	}
+}
+
+func TestParseGameRecordErrors(t *testing.T) {
+
+	header := "[Date \"2020.01.31\"]\n[RuleSet \"classic\"]\n[Players \"1 2\"]\n[Result \"*\"]\n"
+
+	tt := []struct {
+		name string
+		text string
+		line string
+	}{
+		{"When a tag is missing", "[Date \"2020.01.31\"]\n[RuleSet \"classic\"]\n[Result \"*\"]\n", "Line 3"},
+		{"When a tag is unknown", "[Event \"Open\"]\n" + header, "Line 1"},
+		{"When the rule set is unknown", strings.Replace(header, "classic", "chess", 1), "Line 4"},
+		{"When the winner is not a player", strings.Replace(header, "*", "3", 1), "Line 4"},
+		{"When a location is off the board", header + "Fleet 1: A1 A11\n", "Line 5"},
+		{"When a location has no column", header + "Fleet 1: A\n", "Line 5"},
+		{"When a fleet is given twice", header + "Fleet 1: A1 A2\nFleet 1: B1 B2\n", "Line 6"},
+		{"When a player is not in the game", header + "\n1. 1>3 A1\n", "Line 6"},
+		{"When the moves are out of order", header + "\n2. 1>2 A1\n1. 2>1 A1\n", "Line 7"},
+		{"When a relocation changes the size of the ship", header + "\n1. 1 moves A1 A2 > B1\n", "Line 6"},
+		{"When a fleet comes after the moves", header + "\n1. 1>2 A1\nFleet 1: A1 A2\n", "Line 7"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseGameRecord(tc.text)
+
+			if err == nil || !strings.HasPrefix(err.Error(), tc.line+":") {
+				t.Fatalf("Expecting an error on %s but was %v", tc.line, err)
+			}
+		})
+	}
+}
+
+func TestParseShot(t *testing.T) {
+
+	rules := RuleSets["classic"]
+
+	tt := []struct {
+		name     string
+		field    string
+		location Coord
+		outcome  MoveOutcome
+		valid    bool
+	}{
+		{"When the shot missed", "B3", Coord{X: 1, Y: 2}, OutcomeShipMiss, true},
+		{"When the shot hit a ship", "B3x", Coord{X: 1, Y: 2}, OutcomeShipHit, true},
+		{"When the shot won the game", "J10#", Coord{X: 9, Y: 9}, OutcomeWon, true},
+		{"When the shot has two marks", "B3x+", Coord{}, OutcomeShipSunk, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The marks are tried in the same order every time so the result never changes
+			for i := 0; i < 20; i++ {
+				location, outcome, err := parseShot(rules, tc.field)
+
+				if (err == nil) != tc.valid {
+					t.Fatalf("Expecting %s to be valid %v but got %v", tc.field, tc.valid, err)
+				}
+
+				if tc.valid && (location != tc.location || outcome != tc.outcome) {
+					t.Fatalf("Expecting %v %v but was %v %v", tc.location, tc.outcome, location, outcome)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayGameRecord(t *testing.T) {
+
+	record, _ := ParseGameRecord(classicRecord)
+
+	tt := []struct {
+		name       string
+		moveNumber int
+		location   Coord
+		expected   CellState
+	}{
+		{"When the ship has not moved yet", 2, Coord{X: 0, Y: 8}, CellShip},
+		{"When the ship has moved away", 3, Coord{X: 0, Y: 8}, CellEmpty},
+		{"When the ship has moved there", 3, Coord{X: 0, Y: 9}, CellShip},
+		{"When the moved ship has been hit", 4, Coord{X: 0, Y: 9}, CellHit},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			replay, err := record.replay(tc.moveNumber)
+			if err != nil {
+				t.Fatalf("Expecting the record to replay but got %v", err)
+			}
+
+			board := replay.Boards[1].Board
+			if cell := board.cellAt(tc.location); cell == nil || cell.State != tc.expected {
+				t.Fatalf("Expecting %v at %v after move %d but was %+v", tc.expected, tc.location, tc.moveNumber, cell)
+			}
+		})
+	}
+}
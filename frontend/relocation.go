@@ -0,0 +1,183 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+/*
+RelocateShipEventMessage is sent by the Client to move one of their ships instead of firing.
+Ship is any location of the ship. The ship either moves one location in the Direction or,
+when Rotate is set, turns a quarter clockwise around the Ship location
+*/
+type RelocateShipEventMessage struct {
+	EventMessage
+	Ship      Coord
+	Direction Direction
+	Rotate    bool
+}
+
+// ShipMovedEventMessage is sent to the other players, who only learn which player moved a ship
+type ShipMovedEventMessage struct {
+	PlayerID   int
+	MoveNumber int
+}
+
+// RelocateShip moves one of the player's ships in the game
+func RelocateShip(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message RelocateShipEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	relocateShip(db, producer, gameID, message, userID)
+}
+
+/*
+relocateShip checks the new position of the ship and moves it. Moving a ship takes the
+player's turn. The move is recorded with the shots so it can be replayed, but the other
+players are never told where the ship went
+*/
+func relocateShip(db *sql.DB, producer *kafka.Producer, gameID int, message RelocateShipEventMessage, userID int) {
+
+	if !checkTurn(db, producer, gameID, userID) {
+		return
+	}
+
+	rules := FindRuleSetForGame(db, gameID)
+	if !rules.MovingFleet {
+		PublishErrorEvent(producer, "Ships can not move in this game", userID, gameID)
+		return
+	}
+
+	ships := FindShipsForPlayer(db, gameID, userID)
+
+	shipIndex, to, err := shipRelocation(rules, ships, FindMinesForPlayer(db, gameID, userID),
+		FindShotsAtPlayer(db, gameID, userID), message)
+
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, gameID)
+		return
+	}
+
+	seats := FindSeatsForGame(db, gameID)
+	nextID := nextPlayer(seats, userID)
+
+	now := time.Now()
+	deadline := FindClockForGame(db, gameID).turnDeadline(now, nextID)
+
+	move := Move{
+		Kind:     MoveRelocation,
+		PlayerID: userID,
+		Location: message.Ship,
+	}
+
+	moveNumber := RelocateShipInDatabase(db, gameID, move, nextID, deadline, now, ships[shipIndex], to)
+	if moveNumber == -1 {
+		PublishErrorEvent(producer, "It is not your turn", userID, gameID)
+		return
+	}
+
+	for _, seat := range seats {
+		if seat.PlayerID == userID {
+			continue
+		}
+
+		shipMovedMessage := EventMessage{
+			Event:   ShipMovedEvent,
+			To:      seat.PlayerID,
+			GameID:  gameID,
+			Payload: ShipMovedEventMessage{PlayerID: userID, MoveNumber: moveNumber},
+		}
+
+		shipMovedMessage.Send(producer)
+	}
+
+	PublishGameUpdates(db, producer, gameID)
+	playBotTurn(db, producer, gameID)
+}
+
+/*
+shipRelocation finds the ship at the location in the message and where it would be after
+moving. Only undamaged ships can move and every new location must be on open water that has
+not been fired at yet, away from the other ships and the player's own mines
+*/
+func shipRelocation(rules RuleSet, ships []Ship, mines []Coord, firedAt []Coord, message RelocateShipEventMessage) (int, []Coord, error) {
+
+	shipIndex := -1
+	for i, ship := range ships {
+		if containsCoord(ship.Location, message.Ship) {
+			shipIndex = i
+		}
+	}
+
+	if shipIndex == -1 {
+		return -1, nil, errors.New("There is no ship at that location")
+	}
+
+	ship := ships[shipIndex]
+	for _, location := range ship.Location {
+		if location.Hit {
+			return -1, nil, errors.New("Damaged ships can not move")
+		}
+	}
+
+	var to []Coord
+	if message.Rotate {
+		to = rotateAround(ship.Location, message.Ship)
+	} else {
+		step, ok := directionSteps[message.Direction]
+		if !ok {
+			return -1, nil, errors.New("Unknown direction " + string(message.Direction))
+		}
+
+		for _, location := range ship.Location {
+			to = append(to, Coord{X: location.X + step.X, Y: location.Y + step.Y})
+		}
+	}
+
+	for _, location := range to {
+		if !rules.isOnBoard(location) {
+			return -1, nil, errors.New("Ship would leave the board")
+		}
+
+		if rules.isIsland(location) {
+			return -1, nil, errors.New("Ship would run aground on an island")
+		}
+
+		if containsCoord(mines, location) {
+			return -1, nil, errors.New("Ship would sail onto your own mine")
+		}
+
+		if containsCoord(firedAt, location) {
+			return -1, nil, errors.New("Ship can not move to a location that has been fired at")
+		}
+
+		for i, other := range ships {
+			if i != shipIndex && containsCoord(other.Location, location) {
+				return -1, nil, errors.New("Ship would overlap another ship")
+			}
+		}
+	}
+
+	return shipIndex, to, nil
+}
+
+// rotateAround turns the locations a quarter clockwise around the pivot
+func rotateAround(locations []Coord, pivot Coord) []Coord {
+
+	var rotated []Coord
+	for _, location := range locations {
+		dx, dy := location.X-pivot.X, location.Y-pivot.Y
+		rotated = append(rotated, Coord{X: pivot.X + dy, Y: pivot.Y - dx})
+	}
+
+	return rotated
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShipRelocation(t *testing.T) {
+
+	rules := RuleSets["classic"]
+	rules.Islands = []Coord{Coord{X: 6, Y: 2}}
+
+	ships := []Ship{
+		Ship{Size: 3, Location: []Coord{Coord{X: 4, Y: 2}, Coord{X: 4, Y: 3}, Coord{X: 4, Y: 4}}},
+		Ship{Size: 2, Location: []Coord{Coord{X: 3, Y: 3}, Coord{X: 3, Y: 4}}},
+		Ship{Size: 2, Location: []Coord{Coord{X: 9, Y: 0, Hit: true}, Coord{X: 9, Y: 1}}},
+		Ship{Size: 2, Location: []Coord{Coord{X: 0, Y: 8}, Coord{X: 0, Y: 9}}},
+	}
+
+	mines := []Coord{Coord{X: 4, Y: 5}}
+	firedAt := []Coord{Coord{X: 4, Y: 1}}
+
+	tt := []struct {
+		name     string
+		message  RelocateShipEventMessage
+		expected []Coord
+		valid    bool
+	}{
+		{"When moving down into open water", RelocateShipEventMessage{Ship: Coord{X: 4, Y: 3}, Direction: DirectionDown},
+			[]Coord{Coord{X: 5, Y: 2}, Coord{X: 5, Y: 3}, Coord{X: 5, Y: 4}}, true},
+		{"When rotating around the end of the ship", RelocateShipEventMessage{Ship: Coord{X: 3, Y: 4}, Rotate: true},
+			[]Coord{Coord{X: 2, Y: 4}, Coord{X: 3, Y: 4}}, true},
+		{"When there is no ship at the location", RelocateShipEventMessage{Ship: Coord{X: 0, Y: 0}, Direction: DirectionDown}, nil, false},
+		{"When the ship is damaged", RelocateShipEventMessage{Ship: Coord{X: 9, Y: 1}, Direction: DirectionUp}, nil, false},
+		{"When the ship would leave the board", RelocateShipEventMessage{Ship: Coord{X: 0, Y: 8}, Direction: DirectionUp}, nil, false},
+		{"When the ship would overlap another ship", RelocateShipEventMessage{Ship: Coord{X: 4, Y: 2}, Direction: DirectionUp}, nil, false},
+		{"When the ship would sail onto an island", RelocateShipEventMessage{Ship: Coord{X: 4, Y: 2}, Rotate: true}, nil, false},
+		{"When the ship would sail onto a mine", RelocateShipEventMessage{Ship: Coord{X: 4, Y: 4}, Direction: DirectionRight}, nil, false},
+		{"When the ship would sail onto a location fired at", RelocateShipEventMessage{Ship: Coord{X: 4, Y: 4}, Direction: DirectionLeft}, nil, false},
+		{"When the direction is unknown", RelocateShipEventMessage{Ship: Coord{X: 4, Y: 4}, Direction: "sideways"}, nil, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, to, err := shipRelocation(rules, ships, mines, firedAt, tc.message)
+
+			if tc.valid && err != nil {
+				t.Fatalf("Expecting relocation to be valid but was %s", err.Error())
+			}
+
+			if !tc.valid && err == nil {
+				t.Fatalf("Expecting relocation to be invalid but moved to %v", to)
+			}
+
+			if tc.valid && !reflect.DeepEqual(to, tc.expected) {
+				t.Fatalf("Expecting ship at %v but was %v", tc.expected, to)
+			}
+		})
+	}
+}
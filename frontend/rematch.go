@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// ResignEventMessage is sent by the Client to resign from the game
+type ResignEventMessage struct {
+	EventMessage
+}
+
+// RematchEventMessage is sent to offer or accept a rematch of a completed game
+type RematchEventMessage struct {
+	EventMessage
+}
+
+/*
+Resign takes the player out of their running game. With only one other team left the
+game ends with that team as the winner, otherwise the player is eliminated and the game goes on
+*/
+func Resign(db *sql.DB, producer *kafka.Producer, message ResignEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	seats := FindSeatsForGame(db, gameID)
+	if isEliminated(seats, userID) {
+		PublishErrorEvent(producer, "You have already been eliminated", userID, gameID)
+		return
+	}
+
+	nextID := nextPlayer(seats, userID)
+
+	if teamsLeft(eliminateSeat(seats, userID)) < 2 {
+		if !ResignGame(db, gameID, nextOpponent(seats, userID)) {
+			PublishErrorEvent(producer, "Game is not in progress", userID, gameID)
+			return
+		}
+
+		PublishGameUpdates(db, producer, gameID)
+		return
+	}
+
+	// The turn moves on straight away if it was the player's turn
+	if FindTurnForGame(db, gameID) == userID {
+		now := time.Now()
+		deadline := FindClockForGame(db, gameID).turnDeadline(now, nextID)
+
+		PassTurnForGame(db, gameID, userID, nextID, deadline, now)
+	}
+
+	err := EliminatePlayer(db, gameID, userID)
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, gameID)
+		return
+	}
+
+	PublishGameUpdates(db, producer, gameID)
+
+	playBotTurn(db, producer, gameID)
+}
+
+/*
+OfferRematchForGame offers the opponent a rematch once a game is over. If the opponent has already
+offered a rematch then the offer is accepted. The offer is kept in redis so the opponent can
+accept it through any server
+*/
+func OfferRematchForGame(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message RematchEventMessage, userID int) {
+
+	if !canRematch(db, producer, message.GameID, userID) {
+		return
+	}
+
+	offeredBy := FindRematchOffer(cache, message.GameID)
+
+	if offeredBy != -1 && offeredBy != userID {
+		AcceptRematch(db, cache, producer, message, userID)
+		return
+	}
+
+	OfferRematch(cache, message.GameID, userID)
+
+	rematchMessage := EventMessage{
+		Event:   RematchOfferEvent,
+		To:      FindOpponentForGame(db, message.GameID, userID),
+		GameID:  message.GameID,
+		Payload: RematchEventMessage{EventMessage: EventMessage{GameID: message.GameID}},
+	}
+
+	rematchMessage.Send(producer)
+}
+
+// AcceptRematch creates a new game between the same two players without going through the WaitingQueue
+func AcceptRematch(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message RematchEventMessage, userID int) {
+
+	if !canRematch(db, producer, message.GameID, userID) {
+		return
+	}
+
+	offeredBy := FindRematchOffer(cache, message.GameID)
+
+	if offeredBy == -1 || offeredBy == userID {
+		PublishErrorEvent(producer, "There is no rematch offer to accept", userID, message.GameID)
+		return
+	}
+
+	// Only the first accept creates the game
+	if !ClaimRematchOffer(cache, message.GameID) {
+		PublishErrorEvent(producer, "There is no rematch offer to accept", userID, message.GameID)
+		return
+	}
+
+	// The rematch is played with the same rules and the same days for a move
+	gameID, err := createGame(db, []int{offeredBy, userID}, FindRuleSetForGame(db, message.GameID).Name, FindClockForGame(db, message.GameID).MoveDays, false)
+	if err != nil {
+		PublishErrorEvent(producer, "Could not create game", offeredBy, message.GameID)
+		PublishErrorEvent(producer, "Could not create game", userID, message.GameID)
+		return
+	}
+
+	PublishGameStarted(db, producer, gameID)
+}
+
+// canRematch checks the player was in the game, the game is over and it was a two player game
+func canRematch(db *sql.DB, producer *kafka.Producer, gameID int, userID int) bool {
+
+	if !isPlayerInGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return false
+	}
+
+	if len(FindSeatsForGame(db, gameID)) != 2 {
+		PublishErrorEvent(producer, "Rematches are only for two player games", userID, gameID)
+		return false
+	}
+
+	status, _ := FindGameState(db, gameID)
+	if status == "Started" {
+		PublishErrorEvent(producer, "Game is still in progress", userID, gameID)
+		return false
+	}
+
+	return true
+}
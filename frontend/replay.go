@@ -0,0 +1,204 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// MoveKind is what a player did with their move
+type MoveKind string
+
+const (
+	// MoveShot fires at the opponent
+	MoveShot MoveKind = "shot"
+
+	// MoveRelocation moves or rotates one of the player's own ships
+	MoveRelocation MoveKind = "relocation"
+)
+
+/*
+Move defines a single shot or relocation in the move log of a game. Shot is the position of
+the shot within the volley for games where more than one shot is fired in a move and Target
+is the player fired at. For a relocation From and To are the locations of the ship before
+and after it moved
+*/
+type Move struct {
+	Number    int
+	Shot      int
+	Kind      MoveKind
+	PlayerID  int
+	Target    int
+	Location  Coord
+	Outcome   MoveOutcome
+	ShipID    int
+	From      []Coord
+	To        []Coord
+	CreatedAt time.Time
+}
+
+// ReplayEventMessage is sent from the Client to the Server to ask for the state of a game after a move
+type ReplayEventMessage struct {
+	EventMessage
+	MoveNumber int
+}
+
+// GameReplay is the state of every board in a game after a move
+type GameReplay struct {
+	GameID     int
+	MoveNumber int
+	Moves      []Move
+	Boards     []PlayerBoard
+}
+
+// PlayerBoard is the full board of a player with their ships and every shot fired at them
+type PlayerBoard struct {
+	PlayerID int
+	Board    GameBoard
+}
+
+/*
+ReplayGame rebuilds the boards of every player after the move number from the move log.
+A move number of 0 gives the boards as placed and a move number past the end of the
+game gives the final boards
+*/
+func ReplayGame(db *sql.DB, gameID int, moveNumber int) GameReplay {
+
+	var players []int
+	for _, seat := range FindSeatsForGame(db, gameID) {
+		players = append(players, seat.PlayerID)
+	}
+
+	fleets := make(map[int][]Ship)
+	for _, playerID := range players {
+		fleets[playerID] = FindShipsForPlayer(db, gameID, playerID)
+	}
+
+	rules := FindRuleSetForGame(db, gameID)
+
+	replay := replayMoves(rules.BoardSize, players, fleets, FindMovesForGame(db, gameID), moveNumber)
+	replay.GameID = gameID
+
+	for _, board := range replay.Boards {
+		markMines(board.Board, FindMinesForPlayer(db, gameID, board.PlayerID))
+		markIslands(board.Board, rules.Islands)
+	}
+
+	return replay
+}
+
+// replayMoves plays the moves up to and including the move number against the fleets as they were placed
+func replayMoves(size int, players []int, fleets map[int][]Ship, moves []Move, moveNumber int) GameReplay {
+
+	var replay GameReplay
+
+	// Start from the fleets as they were placed
+	for _, playerID := range players {
+		fleets[playerID] = undoRelocations(resetFleet(fleets[playerID]), moves)
+	}
+
+	shotsAt := make(map[int][]Coord)
+
+	for _, move := range moves {
+		if move.Number > moveNumber {
+			break
+		}
+
+		if move.Kind == MoveRelocation {
+			moveShip(fleets[move.PlayerID], move.ShipID, move.To)
+
+			replay.Moves = append(replay.Moves, move)
+			replay.MoveNumber = move.Number
+			continue
+		}
+
+		resolveShot(fleets[move.Target], move.Location)
+		shotsAt[move.Target] = append(shotsAt[move.Target], move.Location)
+
+		replay.Moves = append(replay.Moves, move)
+		replay.MoveNumber = move.Number
+	}
+
+	for _, playerID := range players {
+		replay.Boards = append(replay.Boards, PlayerBoard{
+			PlayerID: playerID,
+			Board:    buildMyBoard(size, fleets[playerID], shotsAt[playerID]),
+		})
+	}
+
+	return replay
+}
+
+// undoRelocations works back from where the ships are now to where they were placed
+func undoRelocations(ships []Ship, moves []Move) []Ship {
+
+	for i := len(moves) - 1; i >= 0; i-- {
+		if moves[i].Kind == MoveRelocation {
+			moveShip(ships, moves[i].ShipID, moves[i].From)
+		}
+	}
+
+	return ships
+}
+
+// moveShip puts the ship with the ID at the locations. Ships are only moved before they are hit
+func moveShip(ships []Ship, shipID int, locations []Coord) {
+
+	for i := range ships {
+		if ships[i].ID != shipID || len(ships[i].Location) != len(locations) {
+			continue
+		}
+
+		for j, location := range locations {
+			ships[i].Location[j] = Coord{X: location.X, Y: location.Y}
+		}
+	}
+}
+
+// resetFleet copies the fleet without any hits
+func resetFleet(ships []Ship) []Ship {
+
+	var result []Ship
+
+	for _, ship := range ships {
+		ship.Sunk = false
+
+		var locations []Coord
+		for _, location := range ship.Location {
+			locations = append(locations, Coord{X: location.X, Y: location.Y})
+		}
+
+		ship.Location = locations
+		result = append(result, ship)
+	}
+
+	return result
+}
+
+/*
+PublishReplay sends the replay of a completed game to one of its players.
+Games which are still running are not replayed as the replay shows both fleets
+*/
+func PublishReplay(db *sql.DB, producer *kafka.Producer, message ReplayEventMessage, userID int) {
+
+	if !isPlayerInGame(db, message.GameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, message.GameID)
+		return
+	}
+
+	status, _ := FindGameState(db, message.GameID)
+	if status != "Completed" {
+		PublishErrorEvent(producer, "Only completed games can be replayed", userID, message.GameID)
+		return
+	}
+
+	replayMessage := EventMessage{
+		Event:   ReplayResultEvent,
+		To:      userID,
+		GameID:  message.GameID,
+		Payload: ReplayGame(db, message.GameID, message.MoveNumber),
+	}
+
+	replayMessage.Send(producer)
+}
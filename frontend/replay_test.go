@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestReplayGame(t *testing.T) {
+
+	tt := []struct {
+		name                string
+		moveNumber          int
+		expectedMoves       int
+		expectedPlayer1Ship CellState
+		expectedPlayer2Ship CellState
+	}{
+		{"When replaying before any move", 0, 0, CellShip, CellShip},
+		{"When replaying after the first hit", 1, 1, CellShip, CellHit},
+		{"When replaying after the destroyer is sunk", 3, 3, CellHit, CellSunk},
+		{"When replaying past the end of the game", 100, 5, CellHit, CellSunk},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			replay := ReplayGame(db, 1, tc.moveNumber)
+
+			if len(replay.Moves) != tc.expectedMoves {
+				t.Fatalf("Expecting %d moves but was %d", tc.expectedMoves, len(replay.Moves))
+			}
+
+			if len(replay.Boards) != 2 {
+				t.Fatalf("Expecting 2 boards but was %d", len(replay.Boards))
+			}
+
+			// Player 1's cruiser is hit at (0, 1) in move 2
+			if state := replay.Boards[0].Board.Coords[0][1].State; state != tc.expectedPlayer1Ship {
+				t.Fatalf("Expecting player 1 location (0, 1) to be %d but was %d", tc.expectedPlayer1Ship, state)
+			}
+
+			// Player 2's destroyer is hit at (1, 1) in move 1 and sunk in move 3
+			if state := replay.Boards[1].Board.Coords[1][1].State; state != tc.expectedPlayer2Ship {
+				t.Fatalf("Expecting player 2 location (1, 1) to be %d but was %d", tc.expectedPlayer2Ship, state)
+			}
+		})
+	}
+}
+
+func TestReplayRelocation(t *testing.T) {
+
+	// Player 1's destroyer has moved from (0, 0) to (1, 0) and is hit there in move 2
+	fleets := map[int][]Ship{
+		1: []Ship{Ship{ID: 1, Size: 2, Location: []Coord{Coord{X: 1, Y: 0, Hit: true}, Coord{X: 1, Y: 1}}}},
+		2: []Ship{Ship{ID: 2, Size: 2, Location: []Coord{Coord{X: 5, Y: 5}, Coord{X: 5, Y: 6}}}},
+	}
+
+	moves := []Move{
+		Move{Number: 1, Kind: MoveRelocation, PlayerID: 1, Location: Coord{X: 0, Y: 0}, ShipID: 1,
+			From: []Coord{Coord{X: 0, Y: 0}, Coord{X: 0, Y: 1}}, To: []Coord{Coord{X: 1, Y: 0}, Coord{X: 1, Y: 1}}},
+		Move{Number: 2, Kind: MoveShot, PlayerID: 2, Target: 1, Location: Coord{X: 1, Y: 0}, Outcome: OutcomeShipHit},
+	}
+
+	tt := []struct {
+		name       string
+		moveNumber int
+		before     CellState
+		after      CellState
+	}{
+		{"When replaying before the ship moved", 0, CellShip, CellEmpty},
+		{"When replaying after the ship moved", 1, CellEmpty, CellShip},
+		{"When replaying after the moved ship is hit", 2, CellEmpty, CellHit},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			replay := replayMoves(10, []int{1, 2}, fleets, moves, tc.moveNumber)
+
+			if state := replay.Boards[0].Board.Coords[0][0].State; state != tc.before {
+				t.Fatalf("Expecting location (0, 0) to be %d but was %d", tc.before, state)
+			}
+
+			if state := replay.Boards[0].Board.Coords[1][0].State; state != tc.after {
+				t.Fatalf("Expecting location (1, 0) to be %d but was %d", tc.after, state)
+			}
+		})
+	}
+}
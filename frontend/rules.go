@@ -0,0 +1,260 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ShipSpec defines a ship every player must place. Shape lists the locations of a
+// hull which is not a straight line relative to each other. Ships without a Shape
+// are a straight line of Size locations
+type ShipSpec struct {
+	Name  string
+	Size  int
+	Shape []Coord
+}
+
+// cells are the locations of the ship relative to each other
+func (s ShipSpec) cells() []Coord {
+
+	if len(s.Shape) > 0 {
+		return s.Shape
+	}
+
+	var cells []Coord
+	for i := 0; i < s.Size; i++ {
+		cells = append(cells, Coord{X: 0, Y: i})
+	}
+
+	return cells
+}
+
+// RuleSet defines the board and fleet a game is played with. In Salvo games a player
+// fires one shot for every ship they have afloat. Abilities is how many times each
+// player can use each ability in a game. Islands are on both boards and Mines is
+// how many mines each player places with their fleet. With a MovingFleet a player
+// can move an undamaged ship instead of firing. Players is how many seats the game
+// has, two when it is not set. In Teams games players in alternate seats form two
+// teams that share their hit boards and win together
+type RuleSet struct {
+	Name        string
+	Description string
+	BoardSize   int
+	Fleet       []ShipSpec
+	Salvo       bool
+	Abilities   map[Ability]int
+	Islands     []Coord
+	Mines       int
+	MovingFleet bool
+	Players     int
+	Teams       bool
+}
+
+// DefaultRuleSet is used when a game does not ask for a rule set
+const DefaultRuleSet = "classic"
+
+// RuleSets are the named rule sets games can be played with
+var RuleSets = map[string]RuleSet{
+	"classic": {
+		Name:        "classic",
+		Description: "Classic 10x10 with 5 ships",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Aircraft Carrier", Size: 5},
+			{Name: "Battleship", Size: 4},
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+	},
+	"salvo": {
+		Name:        "salvo",
+		Description: "Salvo 10x10 with a shot for every ship afloat",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Aircraft Carrier", Size: 5},
+			{Name: "Battleship", Size: 4},
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		Salvo: true,
+	},
+	"arsenal": {
+		Name:        "arsenal",
+		Description: "Arsenal 10x10 with sonar, airstrike and torpedo",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Aircraft Carrier", Size: 5},
+			{Name: "Battleship", Size: 4},
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		Abilities: map[Ability]int{
+			AbilitySonar:     2,
+			AbilityAirstrike: 1,
+			AbilityTorpedo:   1,
+		},
+	},
+	"shapes": {
+		Name:        "shapes",
+		Description: "Shapes 10x10 with L and T shaped hulls",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Carrier", Size: 4, Shape: []Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}}},
+			{Name: "Command Ship", Size: 4, Shape: []Coord{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 1}}},
+			{Name: "Cruiser", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+	},
+	"archipelago": {
+		Name:        "archipelago",
+		Description: "Archipelago 10x10 with islands and 2 mines",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Aircraft Carrier", Size: 5},
+			{Name: "Battleship", Size: 4},
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		Islands: []Coord{
+			{X: 2, Y: 2}, {X: 2, Y: 3}, {X: 3, Y: 2},
+			{X: 6, Y: 7}, {X: 7, Y: 6}, {X: 7, Y: 7},
+			{X: 4, Y: 8},
+		},
+		Mines: 2,
+	},
+	"maneuver": {
+		Name:        "maneuver",
+		Description: "Maneuver 10x10 where undamaged ships can move",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Aircraft Carrier", Size: 5},
+			{Name: "Battleship", Size: 4},
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		MovingFleet: true,
+	},
+	"freeforall3": {
+		Name:        "freeforall3",
+		Description: "Free-for-all 10x10 for 3 players with 3 ships",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		Players: 3,
+	},
+	"freeforall4": {
+		Name:        "freeforall4",
+		Description: "Free-for-all 10x10 for 4 players with 3 ships",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		Players: 4,
+	},
+	"teams": {
+		Name:        "teams",
+		Description: "2v2 teams 10x10 with 3 ships",
+		BoardSize:   10,
+		Fleet: []ShipSpec{
+			{Name: "Cruiser", Size: 3},
+			{Name: "Submarine", Size: 3},
+			{Name: "Destroyer", Size: 2},
+		},
+		Players: 4,
+		Teams:   true,
+	},
+	"quick": {
+		Name:        "quick",
+		Description: "Quick 7x7 with 3 ships",
+		BoardSize:   7,
+		Fleet: []ShipSpec{
+			{Name: "Cruiser", Size: 3},
+			{Name: "Destroyer", Size: 2},
+			{Name: "Patrol Boat", Size: 2},
+		},
+	},
+}
+
+// FindRuleSet finds a rule set by name. An empty name gives the default rule set
+func FindRuleSet(name string) (RuleSet, bool) {
+
+	if name == "" {
+		name = DefaultRuleSet
+	}
+
+	rules, ok := RuleSets[name]
+	return rules, ok
+}
+
+// FindRuleSetForGame finds the rule set the game is played with
+func FindRuleSetForGame(db *sql.DB, gameID int) RuleSet {
+
+	var name string
+
+	row := db.QueryRow("SELECT ruleSet FROM GAMES WHERE ID = $1", gameID)
+	err := row.Scan(&name)
+
+	if err != nil {
+		log.Printf("Error reading from database %s", err.Error())
+	}
+
+	rules, ok := FindRuleSet(name)
+	if !ok {
+		log.Printf("Game %d has unknown rule set %s", gameID, name)
+		return RuleSets[DefaultRuleSet]
+	}
+
+	return rules
+}
+
+// fleetShapes lists the shape of every ship in the fleet
+func (r RuleSet) fleetShapes() [][]Coord {
+
+	var shapes [][]Coord
+	for _, ship := range r.Fleet {
+		shapes = append(shapes, ship.cells())
+	}
+
+	return shapes
+}
+
+// seats is how many players the game is played by
+func (r RuleSet) seats() int {
+
+	if r.Players < 2 {
+		return 2
+	}
+
+	return r.Players
+}
+
+// teamFor is the team of the player in the seat. Teams alternate around the table so
+// teammates take turns in between the other team
+func (r RuleSet) teamFor(seat int) int {
+
+	if r.Teams {
+		return seat % 2
+	}
+
+	return seat
+}
+
+// isOnBoard checks if the coordinate is within the board
+func (r RuleSet) isOnBoard(location Coord) bool {
+	return location.X >= 0 && location.X < r.BoardSize && location.Y >= 0 && location.Y < r.BoardSize
+}
+
+// isIsland checks if the coordinate is an island
+func (r RuleSet) isIsland(location Coord) bool {
+	return containsCoord(r.Islands, location)
+}
@@ -60,6 +60,7 @@ func handleSocketConnection(db *sql.DB, cache *redis.Client, producer *kafka.Pro
 	})
 
 	allSockets[userID] = conn
+	defer StopWatchingAll(db, conn)
 
 	for {
 		_, p, err := conn.ReadMessage()
@@ -72,7 +73,9 @@ func handleSocketConnection(db *sql.DB, cache *redis.Client, producer *kafka.Pro
 		json.Unmarshal(p, &message)
 
 		if message.Event == JoinEvent {
-			JoinGame(db, cache, producer, conn, userID)
+			var joinMessage JoinEventMessage
+			json.Unmarshal(p, &joinMessage)
+			JoinGame(db, cache, producer, conn, joinMessage, userID)
 		}
 
 		if message.Event == PlaceShipsEvent {
@@ -81,5 +84,157 @@ func handleSocketConnection(db *sql.DB, cache *redis.Client, producer *kafka.Pro
 			log.Printf("Here in %v", placeShipsMessage)
 			PlaceShips(db, cache, producer, placeShipsMessage, userID)
 		}
+
+		if message.Event == MakeMoveEvent {
+			var makeMoveMessage MakeMoveEventMessage
+			json.Unmarshal(p, &makeMoveMessage)
+			MakeMove(db, cache, producer, makeMoveMessage, userID)
+		}
+
+		if message.Event == ReplayEvent {
+			var replayMessage ReplayEventMessage
+			json.Unmarshal(p, &replayMessage)
+			PublishReplay(db, producer, replayMessage, userID)
+		}
+
+		if message.Event == ResignEvent {
+			var resignMessage ResignEventMessage
+			json.Unmarshal(p, &resignMessage)
+			Resign(db, producer, resignMessage, userID)
+		}
+
+		if message.Event == RematchOfferEvent {
+			var rematchMessage RematchEventMessage
+			json.Unmarshal(p, &rematchMessage)
+			OfferRematchForGame(db, cache, producer, rematchMessage, userID)
+		}
+
+		if message.Event == RematchAcceptEvent {
+			var rematchMessage RematchEventMessage
+			json.Unmarshal(p, &rematchMessage)
+			AcceptRematch(db, cache, producer, rematchMessage, userID)
+		}
+
+		if message.Event == AutoPlaceShipsEvent {
+			var autoPlaceMessage AutoPlaceShipsEventMessage
+			json.Unmarshal(p, &autoPlaceMessage)
+			AutoPlaceShips(db, producer, autoPlaceMessage, userID)
+		}
+
+		if message.Event == UseAbilityEvent {
+			var abilityMessage UseAbilityEventMessage
+			json.Unmarshal(p, &abilityMessage)
+			UseAbility(db, cache, producer, abilityMessage, userID)
+		}
+
+		if message.Event == RelocateShipEvent {
+			var relocateMessage RelocateShipEventMessage
+			json.Unmarshal(p, &relocateMessage)
+			RelocateShip(db, cache, producer, relocateMessage, userID)
+		}
+
+		if message.Event == TeamPingEvent {
+			var pingMessage TeamPingEventMessage
+			json.Unmarshal(p, &pingMessage)
+			SendTeamPing(db, cache, producer, pingMessage, userID)
+		}
+
+		if message.Event == CreateTournamentEvent {
+			var createMessage CreateTournamentEventMessage
+			json.Unmarshal(p, &createMessage)
+			CreateTournament(db, producer, createMessage, userID)
+		}
+
+		if message.Event == JoinTournamentEvent {
+			var tournamentMessage TournamentEventMessage
+			json.Unmarshal(p, &tournamentMessage)
+			JoinTournament(db, producer, tournamentMessage, userID)
+		}
+
+		if message.Event == StartTournamentEvent {
+			var tournamentMessage TournamentEventMessage
+			json.Unmarshal(p, &tournamentMessage)
+			StartTournament(db, producer, tournamentMessage, userID)
+		}
+
+		if message.Event == TournamentUpdateEvent {
+			var tournamentMessage TournamentEventMessage
+			json.Unmarshal(p, &tournamentMessage)
+			SendTournament(db, producer, tournamentMessage, userID)
+		}
+
+		if message.Event == CreateInviteEvent {
+			var createMessage CreateInviteEventMessage
+			json.Unmarshal(p, &createMessage)
+			CreateInvite(db, cache, producer, createMessage, userID)
+		}
+
+		if message.Event == AcceptInviteEvent {
+			var inviteMessage InviteEventMessage
+			json.Unmarshal(p, &inviteMessage)
+			AcceptInvite(db, cache, producer, inviteMessage, userID)
+		}
+
+		if message.Event == CancelInviteEvent {
+			var inviteMessage InviteEventMessage
+			json.Unmarshal(p, &inviteMessage)
+			CancelInvite(cache, producer, inviteMessage, userID)
+		}
+
+		if message.Event == WatchGameEvent {
+			var watchMessage WatchGameEventMessage
+			json.Unmarshal(p, &watchMessage)
+			WatchGame(db, producer, conn, watchMessage, userID)
+		}
+
+		if message.Event == StopWatchingEvent {
+			var watchMessage WatchGameEventMessage
+			json.Unmarshal(p, &watchMessage)
+			StopWatchingGame(db, conn, watchMessage.GameID)
+		}
+
+		if message.Event == LiveGamesEvent {
+			SendLiveGames(db, producer, userID)
+		}
+
+		if message.Event == ChatEvent {
+			var chatMessage ChatEventMessage
+			json.Unmarshal(p, &chatMessage)
+			SendChat(db, cache, producer, chatMessage, userID)
+		}
+
+		if message.Event == ChatHistoryEvent {
+			var historyMessage ChatHistoryEventMessage
+			json.Unmarshal(p, &historyMessage)
+			SendChatHistory(db, cache, producer, historyMessage, userID)
+		}
+
+		if message.Event == MuteEvent {
+			var muteMessage MuteEventMessage
+			json.Unmarshal(p, &muteMessage)
+			MuteChat(cache, producer, muteMessage, userID)
+		}
+
+		if message.Event == GameRecordEvent {
+			var recordMessage GameRecordEventMessage
+			json.Unmarshal(p, &recordMessage)
+			SendGameRecord(db, producer, recordMessage, userID)
+		}
+
+		if message.Event == ImportRecordEvent {
+			var importMessage ImportRecordEventMessage
+			json.Unmarshal(p, &importMessage)
+			ImportGameRecord(producer, importMessage, userID)
+		}
+
+		if message.Event == CorrespondenceGamesEvent {
+			SendCorrespondenceGames(db, producer, userID)
+		}
+
+		if message.Event == OpenGameEvent {
+			var openMessage OpenGameEventMessage
+			json.Unmarshal(p, &openMessage)
+			OpenGame(db, producer, openMessage, userID)
+		}
 	}
 }
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+	"github.com/gorilla/websocket"
+)
+
+// SpectatorSettings defines when spectators get to see the fleets of a game. With a FleetDelay
+// spectators see the fleets as they were after the last move played at least that long ago,
+// without one the fleets are only shown once the game is over
+type SpectatorSettings struct {
+	FleetDelay time.Duration
+}
+
+// Spectating are the spectator settings used by the server
+var Spectating SpectatorSettings
+
+// liveGamesLimit is how many live games are listed for spectators
+const liveGamesLimit = 20
+
+// WatchGameEventMessage is sent by the Client to start or stop watching a game
+type WatchGameEventMessage struct {
+	EventMessage
+}
+
+/*
+SpectatorUpdateEventMessage is the state of a game for its spectators. Boards has what the
+players have found out about each fleet. Fleets has the boards as they were after
+FleetMoveNumber and is left out while the fleets are hidden. Moves is the whole move log when
+a spectator starts watching and the latest move after that. Relocations only say who moved
+*/
+type SpectatorUpdateEventMessage struct {
+	GameID          int
+	Status          string
+	Winner          int
+	Turn            int
+	Rules           RuleSet
+	Boards          []OpponentBoard
+	Fleets          []PlayerBoard
+	FleetMoveNumber int
+	Moves           []Move
+}
+
+// LiveGame is a running game spectators can watch
+type LiveGame struct {
+	GameID     int
+	RuleSet    string
+	Players    []int
+	Moves      int
+	Spectators int
+}
+
+// spectators are the sockets on this server watching each game
+var spectators = struct {
+	sync.Mutex
+	games map[int]map[*websocket.Conn]bool
+}{games: make(map[int]map[*websocket.Conn]bool)}
+
+/*
+LoadSpectatorSettings reads the spectator settings from the environment.
+SPECTATOR_FLEET_DELAY takes a duration such as 2m, leave it out to hide the fleets until the game is over
+*/
+func LoadSpectatorSettings() SpectatorSettings {
+	return SpectatorSettings{
+		FleetDelay: durationFromEnv("SPECTATOR_FLEET_DELAY"),
+	}
+}
+
+// WatchGame subscribes the socket to a running game and sends the user the game so far.
+// Players can not watch their own game as spectators see more than they do
+func WatchGame(db *sql.DB, producer *kafka.Producer, conn *websocket.Conn, message WatchGameEventMessage, userID int) {
+
+	if !IsLiveGame(db, message.GameID) {
+		PublishErrorEvent(producer, "Could not find a live game to watch", userID, message.GameID)
+		return
+	}
+
+	if isPlayerInGame(db, message.GameID, userID) {
+		PublishErrorEvent(producer, "You can not watch a game you are playing in", userID, message.GameID)
+		return
+	}
+
+	if !watchGame(conn, message.GameID) {
+		return
+	}
+
+	err := CountSpectator(db, message.GameID, 1)
+	if err != nil {
+		log.Printf("Error counting spectators of game %d %s", message.GameID, err.Error())
+	}
+
+	// The socket is only written to by the consumer, so the game so far goes out through Kafka
+	snapshotMessage := EventMessage{
+		Event:   SpectatorUpdateEvent,
+		To:      userID,
+		GameID:  message.GameID,
+		Payload: spectatorUpdate(db, message.GameID, time.Now(), true),
+	}
+
+	snapshotMessage.Send(producer)
+}
+
+// StopWatchingGame unsubscribes the socket from the game
+func StopWatchingGame(db *sql.DB, conn *websocket.Conn, gameID int) {
+
+	if !stopWatching(conn, gameID) {
+		return
+	}
+
+	err := CountSpectator(db, gameID, -1)
+	if err != nil {
+		log.Printf("Error counting spectators of game %d %s", gameID, err.Error())
+	}
+}
+
+// StopWatchingAll unsubscribes a closed socket from every game it was watching
+func StopWatchingAll(db *sql.DB, conn *websocket.Conn) {
+
+	spectators.Lock()
+	var games []int
+	for gameID := range spectators.games {
+		games = append(games, gameID)
+	}
+	spectators.Unlock()
+
+	for _, gameID := range games {
+		StopWatchingGame(db, conn, gameID)
+	}
+}
+
+// SendLiveGames sends the player the running games most worth watching
+func SendLiveGames(db *sql.DB, producer *kafka.Producer, userID int) {
+
+	liveGamesMessage := EventMessage{
+		Event:   LiveGamesEvent,
+		To:      userID,
+		Payload: FindLiveGames(db, liveGamesLimit),
+	}
+
+	liveGamesMessage.Send(producer)
+}
+
+// PublishSpectatorUpdate sends the latest state of the game to everyone watching it on any server
+func PublishSpectatorUpdate(db *sql.DB, producer *kafka.Producer, gameID int) {
+
+	if CountSpectators(db, gameID) == 0 {
+		return
+	}
+
+	spectatorMessage := EventMessage{
+		Event:   SpectatorUpdateEvent,
+		GameID:  gameID,
+		Payload: spectatorUpdate(db, gameID, time.Now(), false),
+	}
+
+	spectatorMessage.Send(producer)
+}
+
+// spectatorUpdate builds the state of the game as spectators see it. The whole move log is
+// only sent when asked for
+func spectatorUpdate(db *sql.DB, gameID int, now time.Time, fullFeed bool) SpectatorUpdateEventMessage {
+
+	status, winner := FindGameState(db, gameID)
+	rules := FindRuleSetForGame(db, gameID)
+	moves := FindMovesForGame(db, gameID)
+
+	update := SpectatorUpdateEventMessage{
+		GameID: gameID,
+		Status: status,
+		Winner: winner,
+		Turn:   FindTurnForGame(db, gameID),
+		Rules:  rules,
+	}
+
+	for _, seat := range FindSeatsForGame(db, gameID) {
+		update.Boards = append(update.Boards, OpponentBoard{
+			PlayerID:   seat.PlayerID,
+			Eliminated: seat.Eliminated,
+			Board:      buildOpponentBoard(db, gameID, seat.PlayerID, rules),
+		})
+	}
+
+	if moveNumber, ok := Spectating.fleetMoveNumber(status != "Started", moves, now); ok {
+		replay := ReplayGame(db, gameID, moveNumber)
+		update.Fleets = replay.Boards
+		update.FleetMoveNumber = replay.MoveNumber
+	}
+
+	after := 0
+	if !fullFeed && len(moves) > 0 {
+		after = moves[len(moves)-1].Number - 1
+	}
+
+	update.Moves = feedMoves(moves, after)
+
+	return update
+}
+
+/*
+fleetMoveNumber finds the move spectators can see the fleets after. Once the game is over that
+is the last move. While it is running it is the last move played at least the fleet delay ago.
+Returns false while the fleets are hidden
+*/
+func (s SpectatorSettings) fleetMoveNumber(over bool, moves []Move, now time.Time) (int, bool) {
+
+	if over {
+		if len(moves) == 0 {
+			return 0, true
+		}
+
+		return moves[len(moves)-1].Number, true
+	}
+
+	if s.FleetDelay <= 0 {
+		return 0, false
+	}
+
+	moveNumber, found := 0, false
+	for _, move := range moves {
+		if move.CreatedAt.After(now.Add(-s.FleetDelay)) {
+			break
+		}
+
+		moveNumber, found = move.Number, true
+	}
+
+	return moveNumber, found
+}
+
+// feedMoves lists the moves after the move number for the move feed. Relocations only keep who
+// moved so spectators can not pass on where the ship went
+func feedMoves(moves []Move, after int) []Move {
+
+	var feed []Move
+	for _, move := range moves {
+		if move.Number <= after {
+			continue
+		}
+
+		if move.Kind == MoveRelocation {
+			move = Move{Number: move.Number, Kind: move.Kind, PlayerID: move.PlayerID, CreatedAt: move.CreatedAt}
+		}
+
+		feed = append(feed, move)
+	}
+
+	return feed
+}
+
+// watchGame adds the socket to the spectators of the game. Returns false if it was already watching
+func watchGame(conn *websocket.Conn, gameID int) bool {
+
+	spectators.Lock()
+	defer spectators.Unlock()
+
+	if spectators.games[gameID] == nil {
+		spectators.games[gameID] = make(map[*websocket.Conn]bool)
+	}
+
+	if spectators.games[gameID][conn] {
+		return false
+	}
+
+	spectators.games[gameID][conn] = true
+	return true
+}
+
+// stopWatching takes the socket out of the spectators of the game. Returns false if it was not watching
+func stopWatching(conn *websocket.Conn, gameID int) bool {
+
+	spectators.Lock()
+	defer spectators.Unlock()
+
+	if !spectators.games[gameID][conn] {
+		return false
+	}
+
+	delete(spectators.games[gameID], conn)
+	if len(spectators.games[gameID]) == 0 {
+		delete(spectators.games, gameID)
+	}
+
+	return true
+}
+
+// spectatorSockets lists the sockets on this server watching the game
+func spectatorSockets(gameID int) []*websocket.Conn {
+
+	spectators.Lock()
+	defer spectators.Unlock()
+
+	var sockets []*websocket.Conn
+	for conn := range spectators.games[gameID] {
+		sockets = append(sockets, conn)
+	}
+
+	return sockets
+}
+
+// liveGamesRoute serves the running games most worth watching
+func liveGamesRoute(db *sql.DB, cache *redis.Client) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if getUserID(cache, r) == -1 {
+			http.Error(w, "Not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FindLiveGames(db, liveGamesLimit))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFleetMoveNumber(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	moves := []Move{
+		{Number: 1, CreatedAt: now.Add(-5 * time.Minute)},
+		{Number: 2, CreatedAt: now.Add(-3 * time.Minute)},
+		{Number: 2, Shot: 1, CreatedAt: now.Add(-3 * time.Minute)},
+		{Number: 3, CreatedAt: now.Add(-time.Minute)},
+	}
+
+	tt := []struct {
+		name       string
+		delay      time.Duration
+		over       bool
+		moves      []Move
+		expected   int
+		showFleets bool
+	}{
+		{"When there is no delay", 0, false, moves, 0, false},
+		{"When the game is over", 0, true, moves, 3, true},
+		{"When the game ended before a move", 0, true, nil, 0, true},
+		{"When some moves are older than the delay", 2 * time.Minute, false, moves, 2, true},
+		{"When every move is older than the delay", 30 * time.Second, false, moves, 3, true},
+		{"When no move is older than the delay", 10 * time.Minute, false, moves, 0, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := SpectatorSettings{FleetDelay: tc.delay}
+
+			moveNumber, ok := settings.fleetMoveNumber(tc.over, tc.moves, now)
+
+			if ok != tc.showFleets {
+				t.Fatalf("Expecting fleets shown to be %v but was %v", tc.showFleets, ok)
+			}
+
+			if moveNumber != tc.expected {
+				t.Fatalf("Expecting fleets after move %d but was %d", tc.expected, moveNumber)
+			}
+		})
+	}
+}
+
+func TestFeedMoves(t *testing.T) {
+
+	moves := []Move{
+		{Number: 1, Kind: MoveShot, PlayerID: 1, Target: 2, Location: Coord{X: 1, Y: 1}, Outcome: OutcomeShipMiss},
+		{Number: 2, Kind: MoveRelocation, PlayerID: 2, Location: Coord{X: 4, Y: 4}, ShipID: 7,
+			From: []Coord{{X: 4, Y: 4}}, To: []Coord{{X: 4, Y: 5}}},
+		{Number: 3, Kind: MoveShot, PlayerID: 1, Target: 2, Location: Coord{X: 2, Y: 2}, Outcome: OutcomeShipHit},
+	}
+
+	feed := feedMoves(moves, 1)
+
+	if len(feed) != 2 {
+		t.Fatalf("Expecting 2 moves after move 1 but was %d", len(feed))
+	}
+
+	relocation := feed[0]
+	if relocation.PlayerID != 2 || relocation.Kind != MoveRelocation {
+		t.Fatalf("Expecting the feed to say player 2 moved a ship")
+	}
+
+	if relocation.ShipID != 0 || relocation.From != nil || relocation.To != nil || relocation.Location != (Coord{}) {
+		t.Fatalf("Expecting the feed not to give away where the ship went")
+	}
+
+	if feed[1].Location != moves[2].Location || feed[1].Outcome != OutcomeShipHit {
+		t.Fatalf("Expecting shots to be in the feed as played")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// maxPingLength is the longest text a ping can carry
+const maxPingLength = 200
+
+/*
+TeamPingEventMessage points the player's teammates at a location on the board of the Target,
+an opponent or the player themselves. Text is an optional note to go with it. The Server sends
+the ping on with the PlayerID of the player who sent it
+*/
+type TeamPingEventMessage struct {
+	EventMessage
+	PlayerID int
+	Target   int
+	Location Coord
+	Text     string
+}
+
+// SendTeamPing sends a ping to the teammates of the player in the game
+func SendTeamPing(db *sql.DB, cache *redis.Client, producer *kafka.Producer, message TeamPingEventMessage, userID int) {
+
+	gameID := message.GameID
+
+	if !IsSeatedInLiveGame(db, gameID, userID) {
+		PublishErrorEvent(producer, "Could not find game", userID, gameID)
+		return
+	}
+
+	sendTeamPing(db, producer, gameID, message, userID)
+}
+
+// sendTeamPing checks the ping and passes it on to the teammates of the player only,
+// the other team never learns a ping was sent
+func sendTeamPing(db *sql.DB, producer *kafka.Producer, gameID int, message TeamPingEventMessage, userID int) {
+
+	status, _ := FindGameState(db, gameID)
+	if status != "Started" {
+		PublishErrorEvent(producer, "Game is not in progress", userID, gameID)
+		return
+	}
+
+	seats := FindSeatsForGame(db, gameID)
+	team := teamOf(seats, userID)
+
+	if team == -1 {
+		PublishErrorEvent(producer, "You are not playing in this game", userID, gameID)
+		return
+	}
+
+	if message.Target != 0 && teamOf(seats, message.Target) == -1 {
+		PublishErrorEvent(producer, "Can not ping a player who is not in the game", userID, gameID)
+		return
+	}
+
+	if !FindRuleSetForGame(db, gameID).isOnBoard(message.Location) {
+		PublishErrorEvent(producer, "Ping is off the board", userID, gameID)
+		return
+	}
+
+	if len(message.Text) > maxPingLength {
+		PublishErrorEvent(producer, "Ping is too long", userID, gameID)
+		return
+	}
+
+	for _, seat := range teammates(seats, userID) {
+		pingMessage := EventMessage{
+			Event:  TeamPingEvent,
+			To:     seat.PlayerID,
+			GameID: gameID,
+			Payload: TeamPingEventMessage{
+				EventMessage: EventMessage{GameID: gameID},
+				PlayerID:     userID,
+				Target:       message.Target,
+				Location:     message.Location,
+				Text:         message.Text,
+			},
+		}
+
+		pingMessage.Send(producer)
+	}
+}
+
+// teammates lists the other players on the team of the player
+func teammates(seats []Seat, playerID int) (team []Seat) {
+
+	playerTeam := teamOf(seats, playerID)
+
+	for _, seat := range seats {
+		if seat.PlayerID != playerID && seat.Team == playerTeam {
+			team = append(team, seat)
+		}
+	}
+
+	return team
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-redis/redis"
+)
+
+// TournamentStatus is where a tournament is in its life
+type TournamentStatus string
+
+const (
+	TournamentSigningUp TournamentStatus = "SigningUp"
+	TournamentStarted   TournamentStatus = "Started"
+	TournamentCompleted TournamentStatus = "Completed"
+	TournamentCancelled TournamentStatus = "Cancelled"
+)
+
+/*
+Tournament is a series of two player games played over rounds. Round is the round being
+played, 0 before the first round. Players who have not placed their ships by the
+RoundDeadline forfeit their match. A tournament with StartsAt set starts on its own then,
+otherwise the organizer starts it
+*/
+type Tournament struct {
+	ID            int
+	Name          string
+	Format        TournamentFormat
+	RuleSet       string
+	Organizer     int
+	Status        TournamentStatus
+	Rounds        int
+	Round         int
+	StartsAt      *time.Time
+	RoundDeadline *time.Time
+	Winner        int
+	Players       []TournamentPlayer
+	Matches       []TournamentMatch
+}
+
+// TournamentSettings defines how long players have to show up for a tournament match
+type TournamentSettings struct {
+	NoShowTimeout time.Duration
+}
+
+// Tournaments are the tournament settings used by the server
+var Tournaments TournamentSettings
+
+// tournamentCheckInterval is how often the servers look for tournaments to move on
+const tournamentCheckInterval = time.Second
+
+// defaultNoShowTimeout is used when TOURNAMENT_NO_SHOW_TIMEOUT is not set
+const defaultNoShowTimeout = 10 * time.Minute
+
+// CreateTournamentEventMessage is sent by the Client to organize a tournament
+type CreateTournamentEventMessage struct {
+	EventMessage
+	Name     string
+	Format   TournamentFormat
+	RuleSet  string
+	Rounds   int
+	StartsAt *time.Time
+}
+
+// TournamentEventMessage is sent by the Client to sign up to, start or look at a tournament
+type TournamentEventMessage struct {
+	EventMessage
+	TournamentID int
+}
+
+// TournamentUpdateEventMessage is the state of a tournament and the standings of its players
+type TournamentUpdateEventMessage struct {
+	Tournament Tournament
+	Standings  []Standing
+}
+
+/*
+LoadTournamentSettings reads the tournament settings from the environment.
+TOURNAMENT_NO_SHOW_TIMEOUT takes a duration such as 10m, it defaults to 10 minutes
+*/
+func LoadTournamentSettings() TournamentSettings {
+
+	settings := TournamentSettings{
+		NoShowTimeout: durationFromEnv("TOURNAMENT_NO_SHOW_TIMEOUT"),
+	}
+
+	if settings.NoShowTimeout == 0 {
+		settings.NoShowTimeout = defaultNoShowTimeout
+	}
+
+	return settings
+}
+
+// roundDeadline works out when players of a round starting now must have placed their ships
+func (s TournamentSettings) roundDeadline(now time.Time) *time.Time {
+
+	if s.NoShowTimeout <= 0 {
+		return nil
+	}
+
+	deadline := now.Add(s.NoShowTimeout)
+	return &deadline
+}
+
+// CreateTournament creates a tournament organized by the player and signs them up to it
+func CreateTournament(db *sql.DB, producer *kafka.Producer, message CreateTournamentEventMessage, userID int) {
+
+	if message.Name == "" {
+		PublishErrorEvent(producer, "Tournament needs a name", userID, 0)
+		return
+	}
+
+	if !message.Format.isValid() {
+		PublishErrorEvent(producer, "Unknown tournament format", userID, 0)
+		return
+	}
+
+	rules, ok := FindRuleSet(message.RuleSet)
+	if !ok {
+		PublishErrorEvent(producer, "Unknown rule set", userID, 0)
+		return
+	}
+
+	if rules.seats() != 2 {
+		PublishErrorEvent(producer, "Tournament games are played by two players", userID, 0)
+		return
+	}
+
+	if message.Rounds < 0 || (message.Rounds > 0 && message.Format != Swiss) {
+		PublishErrorEvent(producer, "Only Swiss tournaments have a set number of rounds", userID, 0)
+		return
+	}
+
+	tournamentID, err := CreateTournamentInDatabase(db, Tournament{
+		Name:      message.Name,
+		Format:    message.Format,
+		RuleSet:   rules.Name,
+		Organizer: userID,
+		Rounds:    message.Rounds,
+		StartsAt:  message.StartsAt,
+	})
+
+	if err != nil {
+		PublishErrorEvent(producer, err.Error(), userID, 0)
+		return
+	}
+
+	SignUpForTournament(db, tournamentID, userID)
+	PublishTournamentUpdate(db, producer, tournamentID)
+}
+
+// JoinTournament signs the player up to a tournament
+func JoinTournament(db *sql.DB, producer *kafka.Producer, message TournamentEventMessage, userID int) {
+
+	if !SignUpForTournament(db, message.TournamentID, userID) {
+		PublishErrorEvent(producer, "Could not sign up to the tournament", userID, 0)
+		return
+	}
+
+	PublishTournamentUpdate(db, producer, message.TournamentID)
+}
+
+// StartTournament lets the organizer close sign up and start the first round
+func StartTournament(db *sql.DB, producer *kafka.Producer, message TournamentEventMessage, userID int) {
+
+	tournament, err := FindTournament(db, message.TournamentID)
+	if err != nil {
+		PublishErrorEvent(producer, "Could not find tournament", userID, 0)
+		return
+	}
+
+	if tournament.Organizer != userID {
+		PublishErrorEvent(producer, "Only the organizer can start the tournament", userID, 0)
+		return
+	}
+
+	if len(tournament.Players) < 2 {
+		PublishErrorEvent(producer, "Tournament needs at least two players", userID, 0)
+		return
+	}
+
+	if !StartTournamentInDatabase(db, tournament.ID) {
+		PublishErrorEvent(producer, "Tournament has already started", userID, 0)
+		return
+	}
+
+	advanceTournament(db, producer, tournament.ID, time.Now())
+}
+
+// SendTournament sends the state of a tournament to the player who asked for it
+func SendTournament(db *sql.DB, producer *kafka.Producer, message TournamentEventMessage, userID int) {
+
+	tournament, err := FindTournament(db, message.TournamentID)
+	if err != nil {
+		PublishErrorEvent(producer, "Could not find tournament", userID, 0)
+		return
+	}
+
+	tournamentMessage := EventMessage{
+		Event:   TournamentUpdateEvent,
+		To:      userID,
+		Payload: tournamentUpdate(tournament),
+	}
+
+	tournamentMessage.Send(producer)
+}
+
+// PublishTournamentUpdate sends the state of the tournament to the organizer and every player
+func PublishTournamentUpdate(db *sql.DB, producer *kafka.Producer, tournamentID int) {
+
+	tournament, err := FindTournament(db, tournamentID)
+	if err != nil {
+		log.Printf("Error reading tournament %d %s", tournamentID, err.Error())
+		return
+	}
+
+	update := tournamentUpdate(tournament)
+
+	recipients := []int{tournament.Organizer}
+	for _, player := range tournament.Players {
+		if player.PlayerID != tournament.Organizer {
+			recipients = append(recipients, player.PlayerID)
+		}
+	}
+
+	for _, playerID := range recipients {
+		tournamentMessage := EventMessage{
+			Event:   TournamentUpdateEvent,
+			To:      playerID,
+			Payload: update,
+		}
+
+		tournamentMessage.Send(producer)
+	}
+}
+
+// tournamentUpdate works out the standings to send with the tournament
+func tournamentUpdate(tournament Tournament) TournamentUpdateEventMessage {
+
+	table := standings(tournament.Players, tournament.Matches)
+	if tournament.Format == Swiss {
+		table = swissRanking(table)
+	}
+
+	return TournamentUpdateEventMessage{Tournament: tournament, Standings: table}
+}
+
+/*
+WatchTournaments starts tournaments when they are due, settles matches once their games are
+over or a player has not shown up and creates the games of the next round. Every server runs
+this. Each change is claimed in the database first so it only happens once
+*/
+func WatchTournaments(db *sql.DB, producer *kafka.Producer) {
+
+	for range time.Tick(tournamentCheckInterval) {
+		now := time.Now()
+
+		for _, tournamentID := range FindTournamentsToAdvance(db, now) {
+			advanceTournament(db, producer, tournamentID, now)
+		}
+	}
+}
+
+// advanceTournament moves the tournament on as far as it can go now
+func advanceTournament(db *sql.DB, producer *kafka.Producer, tournamentID int, now time.Time) {
+
+	tournament, err := FindTournament(db, tournamentID)
+	if err != nil {
+		log.Printf("Error reading tournament %d %s", tournamentID, err.Error())
+		return
+	}
+
+	if tournament.Status == TournamentSigningUp {
+		if tournament.StartsAt == nil || now.Before(*tournament.StartsAt) {
+			return
+		}
+
+		// Nobody to play against
+		if len(tournament.Players) < 2 {
+			if CancelTournament(db, tournamentID) {
+				PublishTournamentUpdate(db, producer, tournamentID)
+			}
+			return
+		}
+
+		if !StartTournamentInDatabase(db, tournamentID) {
+			return
+		}
+
+		advanceTournament(db, producer, tournamentID, now)
+		return
+	}
+
+	if tournament.Status != TournamentStarted {
+		return
+	}
+
+	settled := false
+	for _, match := range tournament.Matches {
+		if match.Round == tournament.Round && match.Winner == 0 {
+			settled = settleMatch(db, producer, tournament, match, now) || settled
+		}
+	}
+
+	if settled {
+		tournament, err = FindTournament(db, tournamentID)
+		if err != nil {
+			log.Printf("Error reading tournament %d %s", tournamentID, err.Error())
+			return
+		}
+	}
+
+	if !roundComplete(tournament.Matches, tournament.Round) {
+		if settled {
+			PublishTournamentUpdate(db, producer, tournamentID)
+		}
+		return
+	}
+
+	matches, winner := nextRound(tournament.Format, tournament.Rounds, tournament.Round, tournament.Players, tournament.Matches)
+
+	if winner != 0 || len(matches) == 0 {
+		if CompleteTournament(db, tournamentID, winner) {
+			PublishTournamentUpdate(db, producer, tournamentID)
+		}
+		return
+	}
+
+	startRound(db, producer, tournament, matches, now)
+}
+
+/*
+settleMatch records the winner of a match once its game is over. When the round deadline has
+passed and a player still has not placed their ships they forfeit. If neither player shows up
+the better seed goes through. Returns true if the match was settled
+*/
+func settleMatch(db *sql.DB, producer *kafka.Producer, tournament Tournament, match TournamentMatch, now time.Time) bool {
+
+	status, winner := FindGameState(db, match.GameID)
+
+	if status == "Completed" && winner != 0 {
+		return RecordMatchWinner(db, match.ID, winner, false)
+	}
+
+	if status == "Started" {
+		if tournament.RoundDeadline == nil || now.Before(*tournament.RoundDeadline) {
+			return false
+		}
+
+		var placed []int
+		for _, playerID := range []int{match.Player1, match.Player2} {
+			if len(FindShipsForPlayer(db, match.GameID, playerID)) > 0 {
+				placed = append(placed, playerID)
+			}
+		}
+
+		// Both players are in the game, the game clocks decide how long it takes
+		if len(placed) == 2 {
+			return false
+		}
+
+		if len(placed) == 1 {
+			if !ResignGame(db, match.GameID, placed[0]) {
+				return false
+			}
+
+			PublishGameUpdates(db, producer, match.GameID)
+			return RecordMatchWinner(db, match.ID, placed[0], true)
+		}
+
+		err := AbandonGame(db, match.GameID)
+		if err != nil {
+			log.Printf("Error ending game %d %s", match.GameID, err.Error())
+			return false
+		}
+
+		PublishGameUpdates(db, producer, match.GameID)
+	} else if status != "Abandoned" {
+		// The game could not be read, the match is settled on a later sweep
+		log.Printf("Error settling match %d, game %d is %s", match.ID, match.GameID, status)
+		return false
+	}
+
+	// The game ended without a winner
+	return RecordMatchWinner(db, match.ID, betterSeed(tournament.Players, match.Player1, match.Player2), true)
+}
+
+// betterSeed picks whichever of the two players was seeded higher
+func betterSeed(players []TournamentPlayer, player1 int, player2 int) int {
+
+	for _, player := range players {
+		if player.PlayerID == player1 || player.PlayerID == player2 {
+			return player.PlayerID
+		}
+	}
+
+	return player1
+}
+
+// startRound creates a game for every match of the next round and tells the players
+func startRound(db *sql.DB, producer *kafka.Producer, tournament Tournament, matches []TournamentMatch, now time.Time) {
+
+	games, ok := StartTournamentRound(db, tournament, matches, Tournaments.roundDeadline(now))
+	if !ok {
+		return
+	}
+
+	PublishTournamentUpdate(db, producer, tournament.ID)
+
+	for _, gameID := range games {
+		PublishGameStarted(db, producer, gameID)
+	}
+}
+
+// tournamentsRoute serves the open tournaments, or a single tournament when asked for by id
+func tournamentsRoute(db *sql.DB, cache *redis.Client) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if getUserID(cache, r) == -1 {
+			http.Error(w, "Not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var body interface{}
+
+		if id := r.URL.Query().Get("id"); id != "" {
+			tournamentID, err := strconv.Atoi(id)
+			if err != nil {
+				http.Error(w, "Invalid tournament id", http.StatusBadRequest)
+				return
+			}
+
+			tournament, err := FindTournament(db, tournamentID)
+			if err != nil {
+				http.Error(w, "Could not find tournament", http.StatusNotFound)
+				return
+			}
+
+			body = tournamentUpdate(tournament)
+		} else {
+			var tournaments []Tournament
+			for _, tournamentID := range FindOpenTournaments(db) {
+				tournament, err := FindTournament(db, tournamentID)
+				if err == nil {
+					tournaments = append(tournaments, tournament)
+				}
+			}
+
+			body = tournaments
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}
+}
@@ -1,7 +1,8 @@
 CREATE TABLE USERS (
   Id bigserial primary key, 
   email text, 
-  password text
+  password text,
+  bot text UNIQUE
 );
 
 INSERT INTO USERS (email, password) 
@@ -12,9 +13,29 @@ VALUES ('2@a.com', '2');
 
 CREATE TABLE GAMES (
   Id bigserial primary key, 
-  player1 bigint references USERS, 
-  player2 bigint references USERS, 
-  status text DEFAULT 'Started'
+  status text DEFAULT 'Started',
+  ruleSet text DEFAULT 'classic',
+  winner bigint references USERS,
+  turn bigint references USERS,
+  moves int DEFAULT 0,
+  deadline timestamptz,
+  turnStartedAt timestamptz,
+  botGame boolean DEFAULT false,
+  spectators int DEFAULT 0,
+  createdAt timestamptz DEFAULT now(),
+  moveDays int DEFAULT 0
+);
+
+CREATE TABLE SEATS (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  playerID bigint references USERS,
+  seat smallint,
+  team smallint,
+  timeBank bigint,
+  eliminated boolean DEFAULT false,
+  UNIQUE (gameID, seat),
+  UNIQUE (gameID, playerID)
 );
 
 CREATE TABLE SHIPS (
@@ -22,15 +43,112 @@ CREATE TABLE SHIPS (
   gameID bigint references GAMES, 
   playerID bigint references USERS, 
   size int,
-  xlocation1 smallint,
-  ylocation1 smallint,
-  xlocation2 smallint,
-  ylocation2 smallint,
-  xlocation3 smallint,
-  ylocation3 smallint,
-  xlocation4 smallint,
-  ylocation4 smallint,
-  xlocation5 smallint,
-  ylocation5 smallint,
-  sunk boolean
-);
\ No newline at end of file
+  sunk boolean DEFAULT false
+);
+
+CREATE TABLE SHIP_LOCATIONS (
+  Id bigserial primary key,
+  shipID bigint references SHIPS,
+  position smallint,
+  xlocation smallint,
+  ylocation smallint,
+  hit boolean DEFAULT false,
+  UNIQUE (shipID, position)
+);
+
+CREATE TABLE MOVES (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  moveNumber int,
+  shot smallint DEFAULT 0,
+  kind text DEFAULT 'shot',
+  playerID bigint references USERS,
+  target bigint references USERS,
+  xlocation smallint,
+  ylocation smallint,
+  outcome smallint,
+  createdAt timestamptz DEFAULT now(),
+  UNIQUE (gameID, moveNumber, shot)
+);
+
+CREATE TABLE ABILITIES (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  playerID bigint references USERS,
+  ability text,
+  moveNumber int,
+  xlocation smallint,
+  ylocation smallint
+);
+
+CREATE TABLE MINES (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  playerID bigint references USERS,
+  xlocation smallint,
+  ylocation smallint
+);
+
+CREATE TABLE RELOCATIONS (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  moveNumber int,
+  shipID bigint references SHIPS,
+  position smallint,
+  fromX smallint,
+  fromY smallint,
+  toX smallint,
+  toY smallint
+);
+
+CREATE TABLE REVEALS (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  playerID bigint references USERS,
+  moveNumber int,
+  xlocation smallint,
+  ylocation smallint
+);
+
+CREATE TABLE TOURNAMENTS (
+  Id bigserial primary key,
+  name text,
+  format text,
+  ruleSet text DEFAULT 'classic',
+  organizer bigint references USERS,
+  status text DEFAULT 'SigningUp',
+  rounds int DEFAULT 0,
+  round int DEFAULT 0,
+  startsAt timestamptz,
+  roundDeadline timestamptz,
+  winner bigint references USERS
+);
+
+CREATE TABLE TOURNAMENT_PLAYERS (
+  Id bigserial primary key,
+  tournamentID bigint references TOURNAMENTS,
+  playerID bigint references USERS,
+  seed int,
+  UNIQUE (tournamentID, playerID)
+);
+
+CREATE TABLE TOURNAMENT_MATCHES (
+  Id bigserial primary key,
+  tournamentID bigint references TOURNAMENTS,
+  round int,
+  slot int,
+  player1 bigint references USERS,
+  player2 bigint references USERS,
+  gameID bigint references GAMES,
+  winner bigint references USERS,
+  forfeit boolean DEFAULT false,
+  UNIQUE (tournamentID, round, slot)
+);
+
+CREATE TABLE CHAT (
+  Id bigserial primary key,
+  gameID bigint references GAMES,
+  playerID bigint references USERS,
+  message text,
+  sentAt timestamptz DEFAULT now()
+);